@@ -1,14 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ViaQ/log-exploration-api/pkg/authorization"
 	healthcontroller "github.com/ViaQ/log-exploration-api/pkg/controllers/health"
 	logscontroller "github.com/ViaQ/log-exploration-api/pkg/controllers/logs"
+	lokicontroller "github.com/ViaQ/log-exploration-api/pkg/controllers/loki"
 	metricscontroller "github.com/ViaQ/log-exploration-api/pkg/controllers/metrics"
 	"github.com/ViaQ/log-exploration-api/pkg/elastic"
+	"github.com/ViaQ/log-exploration-api/pkg/file"
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/ViaQ/log-exploration-api/pkg/loki"
+	"github.com/ViaQ/log-exploration-api/pkg/middleware"
 	"github.com/ViaQ/log-exploration-api/pkg/version"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/ViaQ/log-exploration-api/pkg/configuration"
 	"github.com/gin-gonic/gin"
@@ -16,7 +31,11 @@ import (
 
 func main() {
 	gin.SetMode(gin.ReleaseMode)
-	appConf := configuration.ParseArgs()
+	appConf, err := configuration.ParseArgs()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
 	log, err := initCustomZapLogger(appConf.LogLevel)
 	if err != nil {
@@ -27,18 +46,94 @@ func main() {
 		zap.String("version", version.Version),
 		zap.String("build_time", version.BuildTime))
 
-	repository, err := elastic.NewElasticRepository(log.Named("elasticsearch"), appConf.Elasticsearch)
+	var repository logs.LogsProvider
+	switch appConf.Backend {
+	case "elasticsearch":
+		repository, err = elastic.NewElasticRepository(log.Named("elasticsearch"), appConf.Elasticsearch)
+	case "loki":
+		repository, err = loki.NewLokiRepository(log.Named("loki"), appConf.Loki)
+	case "file":
+		repository, err = file.NewFileRepository(log.Named("file"), appConf.File)
+	default:
+		err = errors.New("unknown backend " + appConf.Backend)
+	}
 	if err != nil {
-		log.Error("unable to create elasticsearch repo", zap.Error(err))
+		log.Error("unable to create "+appConf.Backend+" repo", zap.Error(err))
 		return
 	}
 
+	var authorizer *authorization.Authorizer
+	if appConf.Kubernetes.Authorize {
+		authorizer, err = authorization.NewAuthorizer(log.Named("authorization"), appConf.Kubernetes)
+		if err != nil {
+			log.Error("unable to create kubernetes authorizer", zap.Error(err))
+			return
+		}
+	}
+
+	logs.DefaultMaxLogs = appConf.Server.DefaultMaxLogs
+	logs.MaxLogsLimit = appConf.Server.MaxLogsLimit
+
 	router := gin.New()
+	router.Use(middleware.AddHeader(appConf.Server.CORSOrigins))
 	metricscontroller.NewMetricsController(log.Named("metrics"), router)
-	logscontroller.NewLogsController(log.Named("logs-controller"), repository, router)
+	logsController := logscontroller.NewLogsController(log.Named("logs-controller"), repository, authorizer, router)
+	err = logsController.UseTextTemplate(appConf.TextTemplate)
+	if err != nil {
+		log.Error("invalid text template", zap.Error(err))
+		return
+	}
+	lokicontroller.NewLokiController(log.Named("loki-controller"), repository, authorizer, router)
 	healthcontroller.NewHealthController(router, repository)
 
-	router.Run()
+	// the requests still running when the shutdown timeout elapses are cancelled
+	// through their context, which also abandons their queries
+	requests, cancelRequests := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:         appConf.Server.Address,
+		Handler:      router,
+		ReadTimeout:  appConf.Server.ReadTimeout,
+		WriteTimeout: appConf.Server.WriteTimeout,
+		IdleTimeout:  appConf.Server.IdleTimeout,
+		BaseContext:  func(net.Listener) context.Context { return requests },
+	}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if len(appConf.Server.TLSCert) > 0 {
+			err = server.ListenAndServeTLS(appConf.Server.TLSCert, appConf.Server.TLSKey)
+		} else {
+			err = server.ListenAndServe()
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	select {
+	case <-stopped:
+		log.Error("server stopped", zap.Error(err))
+		return
+	case sig := <-signals:
+		log.Info("shutting down, waiting for in-flight requests", zap.String("signal", sig.String()))
+	}
+	shutdown(log, server, appConf.Server.ShutdownTimeout, cancelRequests)
+	<-stopped
+}
+
+// shutdown stops accepting connections and waits for the requests being
+// served to complete, at most timeout, before cancelling and closing the
+// remaining ones, such as the tail streams.
+func shutdown(log *zap.Logger, server *http.Server, timeout time.Duration, cancelRequests context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		log.Error("in-flight requests did not complete in time, closing their connections", zap.Error(err))
+		cancelRequests()
+		_ = server.Close()
+		return
+	}
+	log.Info("server stopped")
 }
 
 func initCustomZapLogger(level string) (*zap.Logger, error) {
@@ -1,18 +1,23 @@
 #!/bin/sh
 set -eou pipefail
 
-: ${ES_ADDR:="https://localhost:9200"}
-: ${ES_CERT:="admin-cert"}
-: ${ES_KEY:="admin-key"}
-: ${ES_TLS:= false}
+# the former variables are still honoured, every setting can also be given as
+# a LEA_* variable or in the file named by LEA_CONFIG
+[ -n "${ES_ADDR:-}" ] && export LEA_ES_ADDR="${ES_ADDR}"
+[ -n "${ES_CERT:-}" ] && export LEA_ES_CERT="${ES_CERT}"
+[ -n "${ES_KEY:-}" ] && export LEA_ES_KEY="${ES_KEY}"
+[ -n "${ES_TLS:-}" ] && export LEA_ES_TLS="${ES_TLS}"
+[ -n "${K8S_AUTHORIZATION:-}" ] && export LEA_K8S_AUTHORIZATION="${K8S_AUTHORIZATION}"
+
+# the image keeps connecting to https://localhost:9200 by default, unlike the
+# binary, unless a configuration file may set the address
+if [ -z "${LEA_ES_ADDR:-}" ] && [ -z "${LEA_CONFIG:-}" ]; then
+	export LEA_ES_ADDR="https://localhost:9200"
+fi
 
 if [ "$1" = "log-exploration-api" ]; then
-	exec log-exploration-api \
-		-es-addr=${ES_ADDR} \
-		-es-cert=${ES_CERT} \
-		-es-key=${ES_KEY} \
-		-es-tls=${ES_TLS}
+	shift
+	exec log-exploration-api "$@"
 fi
 
 exec "$@"
-
@@ -5,8 +5,10 @@ go 1.16
 require (
 	github.com/elastic/go-elasticsearch/v7 v7.11.0
 	github.com/gin-gonic/gin v1.6.3
+	github.com/gorilla/websocket v1.4.2
 	github.com/prometheus/client_golang v1.11.0
 	go.uber.org/tools v0.0.0-20190618225709-2cfd321de3ee // indirect
 	go.uber.org/zap v1.17.0
+	gopkg.in/yaml.v2 v2.4.0
 	sigs.k8s.io/controller-runtime v0.9.3
 )
@@ -0,0 +1,277 @@
+package authorization
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/configuration"
+	"github.com/ViaQ/log-exploration-api/pkg/constants"
+	"go.uber.org/zap"
+)
+
+// ErrUnauthenticated is returned when the Kubernetes API server does not accept a token.
+var ErrUnauthenticated = errors.New("the token was not accepted by the Kubernetes API server")
+
+const reviewCacheTTL = 30 * time.Second
+
+// UserInfo identifies the user a bearer token belongs to.
+type UserInfo struct {
+	Username string              `json:"username"`
+	UID      string              `json:"uid,omitempty"`
+	Groups   []string            `json:"groups,omitempty"`
+	Extra    map[string][]string `json:"extra,omitempty"`
+}
+
+// Authorizer checks with the Kubernetes API server whether the owner of a token
+// may read the logs of a namespace, the same way `oc logs` is authorized.
+type Authorizer struct {
+	apiServer          string
+	tokenFile          string
+	restrictInfraAudit bool
+	client             *http.Client
+	log                *zap.Logger
+
+	now     func() time.Time
+	mu      sync.Mutex
+	reviews map[string]cachedReview
+	swept   time.Time // when the expired reviews were last evicted
+}
+
+// cachedReview is the outcome of a SubjectAccessReview, or the namespaces
+// visible to a user, until it expires.
+type cachedReview struct {
+	allowed    bool
+	namespaces []string
+	expires    time.Time
+}
+
+func NewAuthorizer(log *zap.Logger, config *configuration.KubernetesConfig) (*Authorizer, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(config.CAFile) > 0 {
+		ca, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", config.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &Authorizer{
+		apiServer:          strings.TrimSuffix(config.APIServer, "/"),
+		tokenFile:          config.TokenFile,
+		restrictInfraAudit: config.RestrictInfraAudit,
+		client:             &http.Client{Transport: transport, Timeout: 10 * time.Second},
+		log:                log,
+		now:                time.Now,
+		reviews:            map[string]cachedReview{},
+	}, nil
+}
+
+// Authenticate returns the user the token belongs to using a TokenReview.
+func (a *Authorizer) Authenticate(ctx context.Context, token string) (UserInfo, error) {
+	review := map[string]interface{}{
+		"apiVersion": "authentication.k8s.io/v1",
+		"kind":       "TokenReview",
+		"spec": map[string]interface{}{
+			"token": token,
+		},
+	}
+	var result struct {
+		Status struct {
+			Authenticated bool     `json:"authenticated"`
+			User          UserInfo `json:"user"`
+			Error         string   `json:"error"`
+		} `json:"status"`
+	}
+	err := a.post(ctx, "/apis/authentication.k8s.io/v1/tokenreviews", review, &result)
+	if err != nil {
+		return UserInfo{}, err
+	}
+	if !result.Status.Authenticated {
+		a.log.Debug("token review rejected the token", zap.String("error", result.Status.Error))
+		return UserInfo{}, ErrUnauthenticated
+	}
+	return result.Status.User, nil
+}
+
+// CanGetPodLogs reports whether the user may get pods/log in the namespace, or
+// in every namespace when the namespace is empty, using a SubjectAccessReview.
+func (a *Authorizer) CanGetPodLogs(ctx context.Context, user UserInfo, namespace string) (bool, error) {
+	key := cacheKey(user, "namespace", namespace)
+	if cached, ok := a.cached(key); ok {
+		return cached.allowed, nil
+	}
+
+	review := map[string]interface{}{
+		"apiVersion": "authorization.k8s.io/v1",
+		"kind":       "SubjectAccessReview",
+		"spec": map[string]interface{}{
+			"resourceAttributes": map[string]interface{}{
+				"namespace":   namespace,
+				"verb":        "get",
+				"resource":    "pods",
+				"subresource": "log",
+			},
+			"user":   user.Username,
+			"uid":    user.UID,
+			"groups": user.Groups,
+			"extra":  user.Extra,
+		},
+	}
+	var result struct {
+		Status struct {
+			Allowed bool `json:"allowed"`
+		} `json:"status"`
+	}
+	err := a.post(ctx, "/apis/authorization.k8s.io/v1/subjectaccessreviews", review, &result)
+	if err != nil {
+		return false, err
+	}
+
+	a.store(key, cachedReview{allowed: result.Status.Allowed})
+	return result.Status.Allowed, nil
+}
+
+// VisibleNamespaces returns the namespaces whose logs the user may read. It
+// returns nil when the user may read the logs of every namespace, which a
+// single cluster-wide review tells. Otherwise every namespace is reviewed and
+// the list is cached like the reviews.
+func (a *Authorizer) VisibleNamespaces(ctx context.Context, user UserInfo) ([]string, error) {
+	allowed, err := a.CanGetPodLogs(ctx, user, "")
+	if err != nil || allowed {
+		return nil, err
+	}
+	key := cacheKey(user, "visible", "")
+	if cached, ok := a.cached(key); ok {
+		return cached.namespaces, nil
+	}
+
+	var namespaceList struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	err = a.do(ctx, http.MethodGet, "/api/v1/namespaces", nil, &namespaceList)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := []string{}
+	for _, item := range namespaceList.Items {
+		allowed, err = a.CanGetPodLogs(ctx, user, item.Metadata.Name)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			namespaces = append(namespaces, item.Metadata.Name)
+		}
+	}
+	a.store(key, cachedReview{namespaces: namespaces})
+	return namespaces, nil
+}
+
+// cacheKey identifies what is cached for a user: their name, uid and groups,
+// which all take part in the reviews, along with the kind of entry and its
+// namespace.
+func cacheKey(user UserInfo, kind string, namespace string) string {
+	groups := append([]string{}, user.Groups...)
+	sort.Strings(groups)
+	return strings.Join([]string{user.Username, user.UID, strings.Join(groups, ","), kind, namespace}, "\x00")
+}
+
+// cached returns the entry under the key unless it expired.
+func (a *Authorizer) cached(key string) (cachedReview, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cached, ok := a.reviews[key]
+	if !ok || !a.now().Before(cached.expires) {
+		return cachedReview{}, false
+	}
+	return cached, true
+}
+
+// store caches the entry for reviewCacheTTL. Expired entries are evicted at
+// most once per TTL, so that the cache only holds the users of the last
+// minute or so.
+func (a *Authorizer) store(key string, review cachedReview) {
+	now := a.now()
+	review.expires = now.Add(reviewCacheTTL)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if now.Sub(a.swept) >= reviewCacheTTL {
+		for key, cached := range a.reviews {
+			if !now.Before(cached.expires) {
+				delete(a.reviews, key)
+			}
+		}
+		a.swept = now
+	}
+	a.reviews[key] = review
+}
+
+// VisibleIndices returns the indices whose logs the user may read. It returns
+// nil when the user may read every index: always, unless the infra and audit
+// logs are restricted to the users who may get pods/log in every namespace.
+func (a *Authorizer) VisibleIndices(ctx context.Context, user UserInfo) ([]string, error) {
+	if !a.restrictInfraAudit {
+		return nil, nil
+	}
+	allowed, err := a.CanGetPodLogs(ctx, user, "")
+	if err != nil || allowed {
+		return nil, err
+	}
+	return []string{constants.AppIndexName}, nil
+}
+
+func (a *Authorizer) post(ctx context.Context, path string, body interface{}, result interface{}) error {
+	return a.do(ctx, http.MethodPost, path, body, result)
+}
+
+// do calls the API server with the service account token of the application.
+func (a *Authorizer) do(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	reader := bytes.NewReader(nil)
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.apiServer+path, reader)
+	if err != nil {
+		return err
+	}
+	token, err := os.ReadFile(a.tokenFile) // read on every call as projected tokens are rotated
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		a.log.Error("failed to call the Kubernetes API server", zap.String("path", path), zap.Error(err))
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		a.log.Error("the Kubernetes API server rejected the request", zap.String("path", path), zap.Int("status", resp.StatusCode))
+		return fmt.Errorf("unexpected status %d from the Kubernetes API server for %s", resp.StatusCode, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
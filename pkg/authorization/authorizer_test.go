@@ -0,0 +1,206 @@
+package authorization
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/configuration"
+	"go.uber.org/zap"
+)
+
+const serviceAccountToken = "service-account-token"
+
+// fakeAPIServer implements the TokenReview, SubjectAccessReview and namespace
+// list APIs of a Kubernetes API server.
+type fakeAPIServer struct {
+	users       map[string]string   // token to username
+	permissions map[string][]string // username to namespaces where pods/log may be read, "" for all
+	namespaces  []string
+	reviews     int
+}
+
+func (f *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+serviceAccountToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var body struct {
+		Spec struct {
+			Token              string `json:"token"`
+			User               string `json:"user"`
+			ResourceAttributes struct {
+				Namespace   string `json:"namespace"`
+				Verb        string `json:"verb"`
+				Resource    string `json:"resource"`
+				Subresource string `json:"subresource"`
+			} `json:"resourceAttributes"`
+		} `json:"spec"`
+	}
+	switch r.URL.Path {
+	case "/apis/authentication.k8s.io/v1/tokenreviews":
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		username, ok := f.users[body.Spec.Token]
+		status := map[string]interface{}{"authenticated": ok}
+		if ok {
+			status["user"] = map[string]interface{}{"username": username, "groups": []string{"system:authenticated"}}
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": status})
+	case "/apis/authorization.k8s.io/v1/subjectaccessreviews":
+		f.reviews++
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		attributes := body.Spec.ResourceAttributes
+		allowed := false
+		if attributes.Verb == "get" && attributes.Resource == "pods" && attributes.Subresource == "log" {
+			for _, namespace := range f.permissions[body.Spec.User] {
+				allowed = allowed || namespace == "" || namespace == attributes.Namespace
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": map[string]interface{}{"allowed": allowed}})
+	case "/api/v1/namespaces":
+		var items []map[string]interface{}
+		for _, namespace := range f.namespaces {
+			items = append(items, map[string]interface{}{"metadata": map[string]interface{}{"name": namespace}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestAuthorizer(t *testing.T) (*Authorizer, *fakeAPIServer) {
+	fake := &fakeAPIServer{
+		users: map[string]string{"admin-token": "admin", "developer-token": "developer"},
+		permissions: map[string][]string{
+			"admin":     {""},
+			"developer": {"app-1", "app-3"},
+		},
+		namespaces: []string{"app-1", "app-2", "app-3", "openshift-logging"},
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(serviceAccountToken+"\n"), 0600); err != nil {
+		t.Fatalf("failed to write the token file. E: %v", err)
+	}
+	authorizer, err := NewAuthorizer(zap.L(), &configuration.KubernetesConfig{
+		Authorize: true,
+		APIServer: server.URL,
+		TokenFile: tokenFile,
+	})
+	if err != nil {
+		t.Fatalf("failed to create the authorizer. E: %v", err)
+	}
+	return authorizer, fake
+}
+
+func TestAuthorizer_Authenticate(t *testing.T) {
+	authorizer, _ := newTestAuthorizer(t)
+
+	user, err := authorizer.Authenticate(context.Background(), "developer-token")
+	if err != nil || user.Username != "developer" {
+		t.Errorf("expected user developer, got %v (%v)", user, err)
+	}
+	_, err = authorizer.Authenticate(context.Background(), "unknown-token")
+	if err != ErrUnauthenticated {
+		t.Errorf("expected error to be %v, got %v", ErrUnauthenticated, err)
+	}
+}
+
+func TestAuthorizer_CanGetPodLogs(t *testing.T) {
+	authorizer, fake := newTestAuthorizer(t)
+	developer := UserInfo{Username: "developer"}
+
+	tests := []struct {
+		namespace string
+		allowed   bool
+	}{
+		{"app-1", true},
+		{"app-2", false},
+		{"", false},
+		{"app-1", true},
+	}
+	for _, tt := range tests {
+		allowed, err := authorizer.CanGetPodLogs(context.Background(), developer, tt.namespace)
+		if err != nil || allowed != tt.allowed {
+			t.Errorf("expected access to %q to be %v, got %v (%v)", tt.namespace, tt.allowed, allowed, err)
+		}
+	}
+	if fake.reviews != 3 {
+		t.Errorf("expected repeated reviews to be cached, got %d reviews", fake.reviews)
+	}
+}
+
+func TestAuthorizer_ReviewCache(t *testing.T) {
+	authorizer, fake := newTestAuthorizer(t)
+	now := time.Date(2021, 3, 17, 14, 0, 0, 0, time.UTC)
+	authorizer.now = func() time.Time { return now }
+
+	developer := UserInfo{Username: "developer", Groups: []string{"b", "a"}}
+	_, _ = authorizer.CanGetPodLogs(context.Background(), developer, "app-1")
+	_, _ = authorizer.CanGetPodLogs(context.Background(), UserInfo{Username: "developer", Groups: []string{"a", "b"}}, "app-1")
+	if fake.reviews != 1 {
+		t.Errorf("expected the order of the groups not to matter, got %d reviews", fake.reviews)
+	}
+	_, _ = authorizer.CanGetPodLogs(context.Background(), UserInfo{Username: "developer", Groups: []string{"a"}}, "app-1")
+	if fake.reviews != 2 {
+		t.Errorf("expected other groups to be reviewed again, got %d reviews", fake.reviews)
+	}
+
+	now = now.Add(reviewCacheTTL)
+	_, _ = authorizer.CanGetPodLogs(context.Background(), developer, "app-2")
+	if fake.reviews != 3 || len(authorizer.reviews) != 1 {
+		t.Errorf("expected the expired reviews to be evicted, got %d reviews and %d cached", fake.reviews, len(authorizer.reviews))
+	}
+}
+
+func TestAuthorizer_VisibleNamespaces(t *testing.T) {
+	authorizer, fake := newTestAuthorizer(t)
+
+	namespaces, err := authorizer.VisibleNamespaces(context.Background(), UserInfo{Username: "admin"})
+	if err != nil || namespaces != nil {
+		t.Errorf("expected no restriction for a cluster-wide user, got %v (%v)", namespaces, err)
+	}
+	namespaces, err = authorizer.VisibleNamespaces(context.Background(), UserInfo{Username: "developer"})
+	if err != nil || !reflect.DeepEqual(namespaces, []string{"app-1", "app-3"}) {
+		t.Errorf("expected namespaces [app-1 app-3], got %v (%v)", namespaces, err)
+	}
+	reviews := fake.reviews
+	namespaces, err = authorizer.VisibleNamespaces(context.Background(), UserInfo{Username: "developer"})
+	if err != nil || !reflect.DeepEqual(namespaces, []string{"app-1", "app-3"}) || fake.reviews != reviews {
+		t.Errorf("expected the visible namespaces to be cached, got %v after %d reviews (%v)", namespaces, fake.reviews-reviews, err)
+	}
+	namespaces, err = authorizer.VisibleNamespaces(context.Background(), UserInfo{Username: "nobody"})
+	if err != nil || namespaces == nil || len(namespaces) != 0 {
+		t.Errorf("expected an empty namespace list, got %v (%v)", namespaces, err)
+	}
+}
+
+func TestAuthorizer_VisibleIndices(t *testing.T) {
+	authorizer, _ := newTestAuthorizer(t)
+	developer := UserInfo{Username: "developer"}
+
+	indices, err := authorizer.VisibleIndices(context.Background(), developer)
+	if err != nil || indices != nil {
+		t.Errorf("expected no restriction without restrictInfraAudit, got %v (%v)", indices, err)
+	}
+
+	authorizer.restrictInfraAudit = true
+	indices, err = authorizer.VisibleIndices(context.Background(), UserInfo{Username: "admin"})
+	if err != nil || indices != nil {
+		t.Errorf("expected no restriction for a cluster-wide user, got %v (%v)", indices, err)
+	}
+	indices, err = authorizer.VisibleIndices(context.Background(), developer)
+	if err != nil || !reflect.DeepEqual(indices, []string{"app"}) {
+		t.Errorf("expected indices [app], got %v (%v)", indices, err)
+	}
+}
@@ -1,27 +1,316 @@
 package configuration
 
-import "flag"
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/constants"
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v2"
+)
 
 type ApplicationConfiguration struct {
-	LogLevel      string
-	Elasticsearch *ElasticsearchConfig
+	ConfigFile    string               `yaml:"-"`
+	LogLevel      string               `yaml:"logLevel"`
+	TextTemplate  string               `yaml:"textTemplate"`
+	Backend       string               `yaml:"backend"`
+	Server        *ServerConfig        `yaml:"server"`
+	Elasticsearch *ElasticsearchConfig `yaml:"elasticsearch"`
+	Loki          *LokiConfig          `yaml:"loki"`
+	File          *FileConfig          `yaml:"file"`
+	Kubernetes    *KubernetesConfig    `yaml:"kubernetes"`
 }
 
 func NewApplicationConfiguration() *ApplicationConfiguration {
 	return &ApplicationConfiguration{
+		Server:        &ServerConfig{},
 		Elasticsearch: &ElasticsearchConfig{},
+		Loki:          &LokiConfig{},
+		File:          &FileConfig{},
+		Kubernetes:    &KubernetesConfig{},
 	}
 }
 
-func ParseArgs() *ApplicationConfiguration {
+// ParseArgs loads the configuration of the process. It exits when the command
+// line cannot be parsed and returns an error when the configuration is invalid.
+func ParseArgs() (*ApplicationConfiguration, error) {
+	return Load(flag.CommandLine, os.Args[1:], os.LookupEnv)
+}
+
+// Load reads the YAML file given with -config, then the environment variables
+// and then the command line arguments, each overriding the settings of the
+// previous ones, and validates the result. Every flag can be set with the
+// environment variable returned by EnvVar.
+func Load(flags *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*ApplicationConfiguration, error) {
 	c := NewApplicationConfiguration()
+	registerFlags(flags, c)
+
+	path, ok := configFlag(args)
+	if !ok {
+		path, _ = lookupEnv(EnvVar("config"))
+	}
+	if len(path) > 0 {
+		err := c.readFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		value, ok := lookupEnv(EnvVar(f.Name))
+		if ok && err == nil {
+			setErr := f.Value.Set(value)
+			if setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %v", value, EnvVar(f.Name), setErr)
+			}
+		}
+		if list, ok := f.Value.(*stringList); ok {
+			// lists given on the command line replace the ones of the environment
+			list.set = false
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	return c, c.Validate()
+}
+
+// EnvVar returns the environment variable setting a flag, LEA_ES_ADDR for -es-addr.
+func EnvVar(flagName string) string {
+	return "LEA_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func registerFlags(flags *flag.FlagSet, c *ApplicationConfiguration) {
+	flags.StringVar(&c.ConfigFile, "config", "", "YAML configuration file, overridden by environment variables and flags")
+	flags.StringVar(&c.LogLevel, "log-level", "info", "application log level (debug | info | warn | error)")
+	flags.StringVar(&c.TextTemplate, "text-template", logs.DefaultTextTemplate, "Go template printing every log entry when plain text is requested")
+	flags.StringVar(&c.Backend, "backend", "elasticsearch", "log store to read logs from (elasticsearch | loki | file)")
+	flags.StringVar(&c.Server.Address, "listen-addr", ":8080", "address the server listens on")
+	flags.StringVar(&c.Server.TLSCert, "tls-cert", "", "server certificate file location, the server uses TLS when set")
+	flags.StringVar(&c.Server.TLSKey, "tls-key", "", "server private key file location")
+	c.Server.CORSOrigins = []string{"*"}
+	flags.Var(&stringList{list: &c.Server.CORSOrigins}, "cors-origins", "origins allowed to make cross-origin requests, can be repeated or comma separated, * allows any origin")
+	flags.IntVar(&c.Server.DefaultMaxLogs, "default-maxlogs", 1000, "number of logs returned when maxlogs is not given")
+	flags.IntVar(&c.Server.MaxLogsLimit, "maxlogs-limit", 1000, "largest maxlogs value accepted")
+	flags.DurationVar(&c.Server.ReadTimeout, "read-timeout", 30*time.Second, "how long reading a request may take")
+	flags.DurationVar(&c.Server.WriteTimeout, "write-timeout", 0, "how long writing a response may take, 0 for no limit as the tail and export streams need")
+	flags.DurationVar(&c.Server.IdleTimeout, "idle-timeout", 2*time.Minute, "how long an idle keep-alive connection is kept open")
+	flags.DurationVar(&c.Server.ShutdownTimeout, "shutdown-timeout", 30*time.Second, "how long in-flight requests are waited for when the server is stopped")
+	flags.BoolVar(&c.Elasticsearch.UseTLS, "es-tls", false, "use TLS for Elasticseach connection")
+	flags.StringVar(&c.Elasticsearch.EsAddress, "es-addr", "http://localhost:9200", "Elasticsearch Server Address")
+	flags.StringVar(&c.Elasticsearch.EsCert, "es-cert", "admin-cert", "admin-cert file location")
+	flags.StringVar(&c.Elasticsearch.EsKey, "es-key", "admin-key", "admin-key file location")
+	flags.StringVar(&c.Elasticsearch.EsCA, "es-ca", "", "CA bundle verifying the Elasticsearch certificate, the system CAs are used when empty")
+	flags.StringVar(&c.Elasticsearch.EsServerName, "es-server-name", "", "name expected in the Elasticsearch certificate when it differs from the host of -es-addr")
+	flags.DurationVar(&c.Elasticsearch.Timeout, "es-timeout", 0, "how long a query to Elasticsearch may take before it is abandoned, 0 waits forever")
+	flags.DurationVar(&c.Elasticsearch.DialTimeout, "es-dial-timeout", 30*time.Second, "how long to wait for a connection to Elasticsearch")
+	flags.StringVar(&c.Elasticsearch.InfraIndex, "es-infra-index", constants.InfraIndexName, "index or alias of the infrastructure logs")
+	flags.StringVar(&c.Elasticsearch.AppIndex, "es-app-index", constants.AppIndexName, "index or alias of the application logs")
+	flags.StringVar(&c.Elasticsearch.AuditIndex, "es-audit-index", constants.AuditIndexName, "index or alias of the audit logs")
+	flags.StringVar(&c.Loki.Address, "loki-addr", "http://localhost:3100", "Loki Server Address, such as https://lokistack-gateway-http:8080/api/logs/v1/application")
+	flags.StringVar(&c.Loki.CAFile, "loki-ca", "", "Loki CA file location, the system CAs are used when empty")
+	flags.Var(&stringList{list: &c.File.Paths}, "file", "NDJSON file of search hits or ViaQ records to serve with the file backend, can be repeated or comma separated")
+	flags.BoolVar(&c.Kubernetes.Authorize, "k8s-authorization", false, "authorize requests with Kubernetes TokenReview and SubjectAccessReview")
+	flags.StringVar(&c.Kubernetes.APIServer, "k8s-addr", "https://kubernetes.default.svc", "Kubernetes API Server Address")
+	flags.StringVar(&c.Kubernetes.TokenFile, "k8s-token", "/var/run/secrets/kubernetes.io/serviceaccount/token", "service account token file location")
+	flags.StringVar(&c.Kubernetes.CAFile, "k8s-ca", "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt", "Kubernetes API Server CA file location")
+	flags.BoolVar(&c.Kubernetes.RestrictInfraAudit, "k8s-restrict-infra-audit", false, "only let callers who may get pods/log in every namespace read the infra and audit logs")
+}
+
+// configFlag finds the value of -config in the arguments, which is needed
+// before parsing them since they override the settings of the file.
+func configFlag(args []string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == "config" && i+1 < len(args) {
+			return args[i+1], true
+		}
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config="), true
+		}
+	}
+	return "", false
+}
+
+// readFile decodes the configuration file into the settings. Unknown keys
+// are rejected so that typos do not go unnoticed.
+func (c *ApplicationConfiguration) readFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the configuration file: %v", err)
+	}
+	// the flags point into the sections, which must not be replaced by empty ones
+	sections := *c
+	err = yaml.UnmarshalStrict(data, c)
+	if err != nil {
+		return fmt.Errorf("invalid configuration file %s: %v", path, err)
+	}
+	if c.Server == nil {
+		c.Server = sections.Server
+	}
+	if c.Elasticsearch == nil {
+		c.Elasticsearch = sections.Elasticsearch
+	}
+	if c.Loki == nil {
+		c.Loki = sections.Loki
+	}
+	if c.File == nil {
+		c.File = sections.File
+	}
+	if c.Kubernetes == nil {
+		c.Kubernetes = sections.Kubernetes
+	}
+	return nil
+}
+
+// Validate checks the settings and reports every invalid one at once, by the
+// name of its flag.
+func (c *ApplicationConfiguration) Validate() error {
+	var problems []string
+	invalid := func(flagName string, format string, args ...interface{}) {
+		problems = append(problems, "-"+flagName+": "+fmt.Sprintf(format, args...))
+	}
 
-	flag.StringVar(&c.LogLevel, "log-level", "info", "application log level (debug | info | warn | error)")
-	flag.BoolVar(&c.Elasticsearch.UseTLS, "es-tls", false, "use TLS for Elasticseach connection")
-	flag.StringVar(&c.Elasticsearch.EsAddress, "es-addr", "http://localhost:9200", "Elasticsearch Server Address")
-	flag.StringVar(&c.Elasticsearch.EsCert, "es-cert", "admin-cert", "admin-cert file location")
-	flag.StringVar(&c.Elasticsearch.EsKey, "es-key", "admin-key", "admin-key file location")
-	flag.Parse()
+	var level zapcore.Level
+	if level.UnmarshalText([]byte(strings.ToLower(c.LogLevel))) != nil {
+		invalid("log-level", "unknown level %q, use debug, info, warn or error", c.LogLevel)
+	}
+	if _, err := logs.NewTextFormatter(c.TextTemplate); err != nil {
+		invalid("text-template", "%v", err)
+	}
+
+	server := c.Server
+	if _, _, err := net.SplitHostPort(server.Address); err != nil {
+		invalid("listen-addr", "%q is not a host:port address", server.Address)
+	}
+	if (len(server.TLSCert) > 0) != (len(server.TLSKey) > 0) {
+		invalid("tls-cert", "the certificate and the key (-tls-key) must be set together")
+	} else if len(server.TLSCert) > 0 {
+		checkFile(invalid, "tls-cert", server.TLSCert)
+		checkFile(invalid, "tls-key", server.TLSKey)
+	}
+	for _, origin := range server.CORSOrigins {
+		if origin != "*" && checkURL(origin, false) != nil {
+			invalid("cors-origins", "%q is neither * nor an origin such as https://console.example.com", origin)
+		}
+	}
+	if server.MaxLogsLimit < 1 {
+		invalid("maxlogs-limit", "must be at least 1, got %d", server.MaxLogsLimit)
+	}
+	if server.DefaultMaxLogs < 1 || server.DefaultMaxLogs > server.MaxLogsLimit {
+		invalid("default-maxlogs", "must be between 1 and -maxlogs-limit (%d), got %d", server.MaxLogsLimit, server.DefaultMaxLogs)
+	}
+	for i, timeout := range []time.Duration{server.ReadTimeout, server.WriteTimeout, server.IdleTimeout, server.ShutdownTimeout} {
+		if timeout < 0 {
+			invalid([]string{"read-timeout", "write-timeout", "idle-timeout", "shutdown-timeout"}[i], "must not be negative")
+		}
+	}
+
+	switch c.Backend {
+	case "elasticsearch":
+		elasticsearch := c.Elasticsearch
+		if err := checkURL(elasticsearch.EsAddress, true); err != nil {
+			invalid("es-addr", "%v", err)
+		}
+		if elasticsearch.UseTLS {
+			checkFile(invalid, "es-cert", elasticsearch.EsCert)
+			checkFile(invalid, "es-key", elasticsearch.EsKey)
+		}
+		if len(elasticsearch.EsCA) > 0 {
+			checkFile(invalid, "es-ca", elasticsearch.EsCA)
+		}
+		if elasticsearch.Timeout < 0 {
+			invalid("es-timeout", "must not be negative")
+		}
+		if elasticsearch.DialTimeout < 0 {
+			invalid("es-dial-timeout", "must not be negative")
+		}
+		for i, index := range []string{elasticsearch.InfraIndex, elasticsearch.AppIndex, elasticsearch.AuditIndex} {
+			if len(index) == 0 || strings.ContainsAny(index, ",*/ ") {
+				invalid([]string{"es-infra-index", "es-app-index", "es-audit-index"}[i], "%q is not an index name", index)
+			}
+		}
+	case "loki":
+		if err := checkURL(c.Loki.Address, true); err != nil {
+			invalid("loki-addr", "%v", err)
+		}
+		if len(c.Loki.CAFile) > 0 {
+			checkFile(invalid, "loki-ca", c.Loki.CAFile)
+		}
+	case "file":
+		if len(c.File.Paths) == 0 {
+			invalid("file", "at least one file is required by the file backend")
+		}
+	default:
+		invalid("backend", "unknown backend %q, use elasticsearch, loki or file", c.Backend)
+	}
+
+	if c.Kubernetes.Authorize {
+		if err := checkURL(c.Kubernetes.APIServer, true); err != nil {
+			invalid("k8s-addr", "%v", err)
+		}
+		checkFile(invalid, "k8s-token", c.Kubernetes.TokenFile)
+		checkFile(invalid, "k8s-ca", c.Kubernetes.CAFile)
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkURL checks that the value is an http or https URL, which may have a
+// path only when allowPath is set.
+func checkURL(value string, allowPath bool) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 || (!allowPath && len(strings.Trim(u.Path, "/")) > 0) {
+		return fmt.Errorf("%q is not an http or https URL", value)
+	}
+	return nil
+}
+
+func checkFile(invalid func(string, string, ...interface{}), flagName string, path string) {
+	_, err := os.Stat(path)
+	if err != nil {
+		invalid(flagName, "%v", err)
+	}
+}
+
+// stringList is a flag holding a list given comma separated or by repeating
+// the flag. The first value replaces the list set by default.
+type stringList struct {
+	list *[]string
+	set  bool
+}
 
-	return c
+func (l *stringList) String() string {
+	if l == nil || l.list == nil {
+		return ""
+	}
+	return strings.Join(*l.list, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	if !l.set {
+		*l.list = nil
+		l.set = true
+	}
+	*l.list = append(*l.list, strings.Split(value, ",")...)
+	return nil
 }
@@ -0,0 +1,154 @@
+package configuration
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func load(args []string, env map[string]string) (*ApplicationConfiguration, error) {
+	flags := flag.NewFlagSet("log-exploration-api", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	return Load(flags, args, func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("failed to write %s. E: %v", name, err)
+	}
+	return path
+}
+
+func TestDefaults(t *testing.T) {
+	c, err := load(nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if c.Backend != "elasticsearch" || c.Server.Address != ":8080" || c.Elasticsearch.EsAddress != "http://localhost:9200" ||
+		c.Server.DefaultMaxLogs != 1000 || c.Server.MaxLogsLimit != 1000 || c.Server.ReadTimeout != 30*time.Second || c.Server.WriteTimeout != 0 || !reflect.DeepEqual(c.Server.CORSOrigins, []string{"*"}) {
+		t.Errorf("unexpected defaults %+v %+v %+v", c, c.Server, c.Elasticsearch)
+	}
+	if indices := c.Elasticsearch.Indices(); !reflect.DeepEqual(indices, []string{"infra", "app", "audit"}) {
+		t.Errorf("expected the default indices, got %v", indices)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+logLevel: debug
+backend: elasticsearch
+server:
+  address: 127.0.0.1:8443
+  corsOrigins: [https://console.example.com]
+  defaultMaxLogs: 100
+  maxLogsLimit: 500
+elasticsearch:
+  address: http://elasticsearch:9200
+  timeout: 30s
+  appIndex: app-logs
+file:
+  paths: [a.ndjson]
+`)
+
+	c, err := load([]string{"-config", path}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if c.ConfigFile != path || c.LogLevel != "debug" || c.Server.Address != "127.0.0.1:8443" || c.Server.DefaultMaxLogs != 100 ||
+		c.Server.MaxLogsLimit != 500 || c.Elasticsearch.Timeout != 30*time.Second || c.Elasticsearch.DialTimeout != 30*time.Second {
+		t.Errorf("expected the settings of the file, got %+v %+v %+v", c, c.Server, c.Elasticsearch)
+	}
+	if indices := c.Elasticsearch.Indices(); !reflect.DeepEqual(indices, []string{"infra", "app-logs", "audit"}) {
+		t.Errorf("expected the application index of the file, got %v", indices)
+	}
+
+	env := map[string]string{
+		"LEA_CONFIG":       path,
+		"LEA_ES_ADDR":      "https://elasticsearch.openshift-logging:9200",
+		"LEA_CORS_ORIGINS": "https://a.example.com,https://b.example.com",
+		"LEA_FILE":         "b.ndjson",
+		"LEA_LISTEN_ADDR":  ":9090",
+	}
+	c, err = load([]string{"-listen-addr=:8081", "-cors-origins", "https://c.example.com", "-cors-origins", "https://d.example.com"}, env)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if c.Elasticsearch.EsAddress != env["LEA_ES_ADDR"] || c.Server.Address != ":8081" || c.Server.DefaultMaxLogs != 100 {
+		t.Errorf("expected flags to override the environment and the environment the file, got %+v %+v", c.Server, c.Elasticsearch)
+	}
+	if !reflect.DeepEqual(c.Server.CORSOrigins, []string{"https://c.example.com", "https://d.example.com"}) || !reflect.DeepEqual(c.File.Paths, []string{"b.ndjson"}) {
+		t.Errorf("expected lists to be replaced, got %v and %v", c.Server.CORSOrigins, c.File.Paths)
+	}
+}
+
+func TestInvalidConfiguration(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Args     []string
+		Env      map[string]string
+		File     string
+		Error    string
+	}{
+		{
+			"Unknown key",
+			nil, nil,
+			"elasticsearch:\n  adress: http://elasticsearch:9200\n",
+			"field adress not found",
+		},
+		{
+			"Invalid environment variable",
+			nil, map[string]string{"LEA_ES_TIMEOUT": "ten seconds"}, "",
+			`invalid value "ten seconds" for LEA_ES_TIMEOUT`,
+		},
+		{
+			"Invalid settings",
+			[]string{"-listen-addr=8080", "-tls-cert=server.crt", "-es-addr=elasticsearch:9200", "-es-app-index=app-*", "-cors-origins=console.example.com", "-default-maxlogs=2000"},
+			nil, "",
+			`invalid configuration: -listen-addr: "8080" is not a host:port address; ` +
+				`-tls-cert: the certificate and the key (-tls-key) must be set together; ` +
+				`-cors-origins: "console.example.com" is neither * nor an origin such as https://console.example.com; ` +
+				`-default-maxlogs: must be between 1 and -maxlogs-limit (1000), got 2000; ` +
+				`-es-addr: "elasticsearch:9200" is not an http or https URL; ` +
+				`-es-app-index: "app-*" is not an index name`,
+		},
+		{
+			"Missing files",
+			[]string{"-backend=file", "-es-addr=not checked", "-k8s-authorization", "-k8s-token=/does/not/exist", "-k8s-ca=/does/not/exist"},
+			nil, "",
+			`invalid configuration: -file: at least one file is required by the file backend; ` +
+				`-k8s-token: stat /does/not/exist: no such file or directory; -k8s-ca: stat /does/not/exist: no such file or directory`,
+		},
+		{
+			"Negative timeouts",
+			[]string{"-write-timeout=-1s", "-es-timeout=-30s"},
+			nil, "",
+			`invalid configuration: -write-timeout: must not be negative; -es-timeout: must not be negative`,
+		},
+		{
+			"Unknown backend",
+			[]string{"-backend=mysql", "-log-level=verbose"},
+			nil, "",
+			`invalid configuration: -log-level: unknown level "verbose", use debug, info, warn or error; -backend: unknown backend "mysql", use elasticsearch, loki or file`,
+		},
+	}
+
+	for _, tt := range tests {
+		args := tt.Args
+		if len(tt.File) > 0 {
+			args = append([]string{"-config=" + writeFile(t, "config.yaml", tt.File)}, args...)
+		}
+		_, err := load(args, tt.Env)
+		if err == nil || !strings.Contains(err.Error(), tt.Error) {
+			t.Errorf("%s: expected error %q, got %v", tt.TestName, tt.Error, err)
+		}
+	}
+}
@@ -1,8 +1,33 @@
 package configuration
 
+import (
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/constants"
+)
+
 type ElasticsearchConfig struct {
-	EsAddress string
-	EsCert    string
-	EsKey     string
-	UseTLS    bool
+	EsAddress    string        `yaml:"address"`
+	EsCert       string        `yaml:"cert"`
+	EsKey        string        `yaml:"key"`
+	EsCA         string        `yaml:"ca"`          // CA bundle verifying Elasticsearch, the system CAs are used when empty
+	EsServerName string        `yaml:"serverName"`  // name expected in the certificate of Elasticsearch instead of the host
+	UseTLS       bool          `yaml:"tls"`         // present the client certificate
+	Timeout      time.Duration `yaml:"timeout"`     // how long a query may take, 0 waits forever
+	DialTimeout  time.Duration `yaml:"dialTimeout"` // how long to wait for a connection to be established
+	InfraIndex   string        `yaml:"infraIndex"`
+	AppIndex     string        `yaml:"appIndex"`
+	AuditIndex   string        `yaml:"auditIndex"`
+}
+
+// Indices returns the indices or aliases holding the infrastructure,
+// application and audit logs, the default ones for the names left empty.
+func (config *ElasticsearchConfig) Indices() []string {
+	indices := []string{config.InfraIndex, config.AppIndex, config.AuditIndex}
+	for i, defaultIndex := range []string{constants.InfraIndexName, constants.AppIndexName, constants.AuditIndexName} {
+		if len(indices[i]) == 0 {
+			indices[i] = defaultIndex
+		}
+	}
+	return indices
 }
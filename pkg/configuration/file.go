@@ -0,0 +1,5 @@
+package configuration
+
+type FileConfig struct {
+	Paths []string `yaml:"paths"`
+}
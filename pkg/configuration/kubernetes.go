@@ -0,0 +1,11 @@
+package configuration
+
+type KubernetesConfig struct {
+	Authorize bool   `yaml:"authorize"`
+	APIServer string `yaml:"apiServer"`
+	TokenFile string `yaml:"tokenFile"`
+	CAFile    string `yaml:"caFile"`
+	// RestrictInfraAudit limits the callers who may not read the logs of every
+	// namespace to the application logs.
+	RestrictInfraAudit bool `yaml:"restrictInfraAudit"`
+}
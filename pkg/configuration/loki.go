@@ -0,0 +1,6 @@
+package configuration
+
+type LokiConfig struct {
+	Address string `yaml:"address"`
+	CAFile  string `yaml:"caFile"`
+}
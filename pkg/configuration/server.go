@@ -0,0 +1,16 @@
+package configuration
+
+import "time"
+
+type ServerConfig struct {
+	Address         string        `yaml:"address"`
+	TLSCert         string        `yaml:"tlsCert"`
+	TLSKey          string        `yaml:"tlsKey"`
+	CORSOrigins     []string      `yaml:"corsOrigins"`
+	DefaultMaxLogs  int           `yaml:"defaultMaxLogs"`
+	MaxLogsLimit    int           `yaml:"maxLogsLimit"`
+	ReadTimeout     time.Duration `yaml:"readTimeout"`     // how long reading a request may take
+	WriteTimeout    time.Duration `yaml:"writeTimeout"`    // how long writing a response may take, 0 for no limit
+	IdleTimeout     time.Duration `yaml:"idleTimeout"`     // how long a keep-alive connection waits for the next request
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"` // how long in-flight requests are waited for on shutdown
+}
@@ -2,7 +2,6 @@ package health
 
 import (
 	"github.com/ViaQ/log-exploration-api/pkg/logs"
-	"github.com/ViaQ/log-exploration-api/pkg/middleware"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
@@ -15,7 +14,6 @@ func NewHealthController(router *gin.Engine, logsProvider logs.LogsProvider) {
 	healthController := &HealthController{
 		healthProvider: logsProvider,
 	}
-	router.Use(middleware.AddHeader())
 	r := router.Group("")
 	//r.Use(middleware.TokenHeader())
 	r.GET("/health", healthController.HealthHandler)
@@ -27,7 +25,7 @@ func (healthController *HealthController) HealthHandler(gctx *gin.Context) {
 }
 
 func (healthController *HealthController) ReadinessHandler(gctx *gin.Context) {
-	checkReadiness := healthController.healthProvider.CheckReadiness()
+	checkReadiness := healthController.healthProvider.CheckReadiness(gctx.Request.Context())
 	if checkReadiness == false {
 		gctx.JSON(http.StatusBadRequest, gin.H{"Message": "failed to connect to esClient"})
 		return
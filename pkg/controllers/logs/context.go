@@ -0,0 +1,71 @@
+package logscontroller
+
+import (
+	"net/http"
+
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseContext is the v1 response of LogContext, where every log is the JSON encoded search hit.
+type ResponseContext struct {
+	Before []string `json:"before"`
+	Entry  string   `json:"entry"`
+	After  []string `json:"after"`
+}
+
+// ResponseContextV2 is the v2 response of LogContext, where every log is a JSON object.
+type ResponseContextV2 struct {
+	Before []logs.LogEntry `json:"before"`
+	Entry  logs.LogEntry   `json:"entry"`
+	After  []logs.LogEntry `json:"after"`
+}
+
+// LogContext returns the log whose document id or viaq_msg_id is given, along
+// with up to "size" logs its container wrote before and after it, oldest first.
+func (controller *LogsController) LogContext(gctx *gin.Context) {
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
+	var options logs.ContextOptions
+	err := gctx.ShouldBindQuery(&options)
+	if err == nil {
+		err = options.Validate()
+	}
+	if err != nil {
+		controller.emitError(gctx, logs.InvalidContextSize())
+		return
+	}
+	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
+	logContext, err := controller.logsProvider.Context(gctx.Request.Context(), params, gctx.Params.ByName("id"), options)
+	if err != nil {
+		controller.emitError(gctx, err)
+		return
+	}
+
+	if wantsText(gctx) {
+		entries := append(append(logContext.Before, logContext.Entry), logContext.After...)
+		controller.emitText(gctx, logs.Page{Logs: entries})
+		return
+	}
+	if gctx.GetInt(apiVersionKey) == 2 {
+		response := &ResponseContextV2{Before: logContext.Before, Entry: logContext.Entry, After: logContext.After}
+		if response.Before == nil {
+			response.Before = []logs.LogEntry{}
+		}
+		if response.After == nil {
+			response.After = []logs.LogEntry{}
+		}
+		gctx.JSON(http.StatusOK, response)
+		return
+	}
+	response := &ResponseContext{Before: []string{}, Entry: logContext.Entry.Hit, After: []string{}}
+	for _, entry := range logContext.Before {
+		response.Before = append(response.Before, entry.Hit)
+	}
+	for _, entry := range logContext.After {
+		response.After = append(response.After, entry.Hit)
+	}
+	gctx.JSON(http.StatusOK, response)
+}
@@ -3,124 +3,297 @@ package logscontroller
 import (
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/ViaQ/log-exploration-api/pkg/authorization"
 	"github.com/ViaQ/log-exploration-api/pkg/logs"
 	"github.com/ViaQ/log-exploration-api/pkg/middleware"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+// ResponseLogs is the v1 response, where every log is the JSON encoded search hit.
 type ResponseLogs struct {
-	Error string   `json:"Error"`
-	Logs  []string `json:"Logs"`
+	Logs       []string `json:"Logs"`
+	NextCursor string   `json:"next_cursor,omitempty"`
 }
 
+// ResponseLogsV2 is the v2 response, where every log is a JSON object.
+type ResponseLogsV2 struct {
+	Logs       []logs.LogEntry `json:"logs"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+const apiVersionKey = "apiVersion"
+
 type LogsController struct {
-	logsProvider logs.LogsProvider
-	log          *zap.Logger
+	logsProvider      logs.LogsProvider
+	authorizer        *authorization.Authorizer
+	log               *zap.Logger
+	tailInterval      time.Duration
+	heartbeatInterval time.Duration
+	textFormatter     *logs.TextFormatter
 }
 
-func NewLogsController(log *zap.Logger, logsProvider logs.LogsProvider, router *gin.Engine) *LogsController {
+// defaultTextFormatter is checked when the package is loaded, so that a broken
+// default template cannot fail a request.
+var defaultTextFormatter = logs.MustTextFormatter(logs.DefaultTextTemplate)
+
+func NewLogsController(log *zap.Logger, logsProvider logs.LogsProvider, authorizer *authorization.Authorizer, router *gin.Engine) *LogsController {
 	controller := &LogsController{
-		log:          log,
-		logsProvider: logsProvider,
+		textFormatter:     defaultTextFormatter,
+		log:               log,
+		logsProvider:      logsProvider,
+		authorizer:        authorizer,
+		tailInterval:      defaultTailInterval,
+		heartbeatInterval: defaultHeartbeatInterval,
 	}
 
-	router.Use(middleware.AddHeader())
-	r := router.Group("logs")
+	controller.registerRoutes(router.Group("logs"), 1)
+	controller.registerRoutes(router.Group("v2/logs"), 2)
+	return controller
+}
+
+func (controller *LogsController) registerRoutes(r *gin.RouterGroup, version int) {
+	r.Use(setAPIVersion(version))
 	r.Use(middleware.TokenHeader())
+	if controller.authorizer != nil {
+		r.Use(middleware.Authorization(controller.authorizer))
+	}
 	r.GET("/filter", controller.FilterLogs)
+	r.GET("/tail", controller.TailLogs)
+	r.GET("/export", controller.ExportLogs)
+	r.GET("/histogram", controller.Histogram)
+	r.GET("/values/:field", controller.Values)
+	r.GET("/context/:id", controller.LogContext)
+	r.POST("/search", controller.Search)
 	r.GET("/namespace/:namespace", controller.FilterNamespaceLogs)
 	r.GET("/namespace/:namespace/pod/:podname", controller.FilterPodLogs)
 	r.GET("/namespace/:namespace/pod/:podname/container/:containername", controller.FilterContainerLogs)
 	r.GET("", controller.Logs)
-	r.GET("/logs/namespace/:namespace/:entity/:entity_name", controller.FilterEntityLogs)
+	r.GET("/namespace/:namespace/entity/:entity/:entity_name", controller.FilterEntityLogs)
 	r.GET("/logs_by_labels/:labels", controller.FilterLabelLogs)
-	return controller
 }
 
+// UseTextTemplate replaces the Go template printing every log entry when
+// plain text is requested.
+func (controller *LogsController) UseTextTemplate(text string) error {
+	textFormatter, err := logs.NewTextFormatter(text)
+	if err != nil {
+		return err
+	}
+	controller.textFormatter = textFormatter
+	return nil
+}
+
+// setAPIVersion records which version of the API the route belongs to, which
+// decides how the logs are rendered.
+func setAPIVersion(version int) gin.HandlerFunc {
+	return func(gctx *gin.Context) {
+		gctx.Set(apiVersionKey, version)
+		gctx.Next()
+	}
+}
+
+// FilterEntityLogs returns the logs of every pod owned by a deployment,
+// statefulset, daemonset, job or cronjob.
 func (controller *LogsController) FilterEntityLogs(gctx *gin.Context) {
-	gctx.JSON(http.StatusOK, gin.H{"Logs": "To Be Implemented"})
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
+	params.Namespace = gctx.Params.ByName("namespace")
+	entity := gctx.Params.ByName("entity")
+	entityName := gctx.Params.ByName("entity_name")
+	_, err := logs.NewWorkloadSelector(entity, entityName)
+	if err != nil {
+		controller.emitError(gctx, err)
+		return
+	}
+	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
+	page, err := controller.logsProvider.FilterEntityLogs(gctx.Request.Context(), params, entity, entityName)
+	controller.emitFilteredLogs(gctx, page, err)
+}
+
+// Histogram counts the logs matching the filter parameters per time bucket.
+func (controller *LogsController) Histogram(gctx *gin.Context) {
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
+	var options logs.HistogramOptions
+	err := gctx.ShouldBindQuery(&options)
+	if err == nil {
+		err = options.Validate()
+	}
+	if err != nil {
+		controller.emitError(gctx, logs.InvalidHistogram())
+		return
+	}
+	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
+	histogram, err := controller.logsProvider.Histogram(gctx.Request.Context(), params, options)
+	if err != nil {
+		controller.emitError(gctx, err)
+		return
+	}
+	gctx.JSON(http.StatusOK, histogram)
+}
+
+// Values lists the values of a field in the logs matching the filter
+// parameters, so that clients can offer autocompletion.
+func (controller *LogsController) Values(gctx *gin.Context) {
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
+	field := gctx.Params.ByName("field")
+	var options logs.ValuesOptions
+	err := gctx.ShouldBindQuery(&options)
+	if err == nil {
+		err = options.Validate(field)
+	}
+	if err != nil {
+		controller.emitError(gctx, logs.InvalidValueField())
+		return
+	}
+	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
+	values, err := controller.logsProvider.Values(gctx.Request.Context(), params, field, options)
+	if err != nil {
+		controller.emitError(gctx, err)
+		return
+	}
+	gctx.JSON(http.StatusOK, values)
 }
 
-func initializeQueryParameters(gctx *gin.Context) logs.Parameters {
+func (controller *LogsController) initializeQueryParameters(gctx *gin.Context) logs.Parameters {
 	var queryParams logs.Parameters
-	err := gctx.Bind(&queryParams)
+	err := gctx.ShouldBindQuery(&queryParams)
 	if err != nil {
-		gctx.JSON(http.StatusInternalServerError, &ResponseLogs{ //If error is not nil, an internal server error might have ocurred
-			Error: err.Error() + ", an internal server error may have occurred",
-			Logs:  nil,
-		})
+		controller.emitError(gctx, logs.InvalidParameters(err))
 	}
+	// "namespace!=value" is read as the parameter "namespace!"
+	for key, values := range gctx.Request.URL.Query() {
+		if name := strings.TrimSuffix(key, "!"); name != key && !queryParams.Exclude(name, strings.Join(values, ",")) && !gctx.IsAborted() {
+			controller.emitError(gctx, logs.InvalidFilter())
+		}
+	}
+	queryParams.Namespaces = middleware.AllowedNamespaces(gctx)
+	queryParams.Indices = middleware.AllowedIndices(gctx)
 	return queryParams
 }
 
-func emitFilteredLogs(gctx *gin.Context, logsList []string, err error) {
+func (controller *LogsController) emitFilteredLogs(gctx *gin.Context, page logs.Page, err error) {
 
 	if err != nil {
-		if err.Error() == logs.NotFoundError().Error() { //If error is not nil, and logs are not nil, implies a user error has occurred
-			gctx.JSON(http.StatusBadRequest, &ResponseLogs{
-				Error: logs.NotFoundError().Error() + ", please check the input parameters",
-				Logs:  logsList,
-			})
-			return
-		} else {
-			gctx.JSON(http.StatusInternalServerError, &ResponseLogs{ //If error is not nil and logs are not nil, implies an internal server error might have ocurred
-				Error: err.Error() + ", an internal server error may have occurred",
-				Logs:  logsList,
-			})
-			return
+		controller.emitError(gctx, err)
+		return
+	}
+	if wantsText(gctx) {
+		controller.emitText(gctx, page)
+		return
+	}
+	if gctx.GetInt(apiVersionKey) == 2 {
+		response := &ResponseLogsV2{Logs: page.Logs, NextCursor: page.NextCursor}
+		if response.Logs == nil {
+			response.Logs = []logs.LogEntry{}
 		}
+		gctx.JSON(http.StatusOK, response)
+		return
+	}
+	hits := make([]string, 0, len(page.Logs))
+	for _, entry := range page.Logs {
+		hits = append(hits, entry.Hit)
+	}
+	if len(hits) == 0 {
+		hits = append(hits, "No logs are present or the entry does not exist")
+	}
+	gctx.JSON(http.StatusOK, &ResponseLogs{Logs: hits, NextCursor: page.NextCursor})
+}
+
+// emitError answers with the status code of the error and its problem
+// details, or its message when plain text is requested. Unexpected errors are
+// logged, as the client is only told that an internal error occurred.
+func (controller *LogsController) emitError(gctx *gin.Context, err error) {
+	problem := logs.NewProblem(err)
+	if problem.Status >= http.StatusInternalServerError {
+		controller.log.Error("failed to serve the request", zap.String("path", gctx.Request.URL.Path), zap.Error(err))
+	}
+	if wantsText(gctx) {
+		gctx.Abort()
+		gctx.String(problem.Status, "%s\n", problem.Detail)
+		return
 	}
-	gctx.JSON(http.StatusOK, gin.H{"Logs": logsList})
+	middleware.AbortWithProblem(gctx, err)
 }
 
 func (controller *LogsController) FilterPodLogs(gctx *gin.Context) {
-	params := initializeQueryParameters(gctx)
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
 	params.Namespace = gctx.Params.ByName("namespace")
 	params.Podname = gctx.Params.ByName("podname")
 	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
-	logsList, err := controller.logsProvider.FilterPodLogs(params)
-	emitFilteredLogs(gctx, logsList, err)
+	page, err := controller.logsProvider.FilterPodLogs(gctx.Request.Context(), params)
+	controller.emitFilteredLogs(gctx, page, err)
 }
 
 func (controller *LogsController) Logs(gctx *gin.Context) {
-	params := initializeQueryParameters(gctx)
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
 	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
-	logsList, err := controller.logsProvider.Logs(params)
-	emitFilteredLogs(gctx, logsList, err)
+	page, err := controller.logsProvider.Logs(gctx.Request.Context(), params)
+	controller.emitFilteredLogs(gctx, page, err)
 }
 
 func (controller *LogsController) FilterNamespaceLogs(gctx *gin.Context) {
-	params := initializeQueryParameters(gctx)
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
 	params.Namespace = gctx.Params.ByName("namespace")
 	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
-	logsList, err := controller.logsProvider.FilterNamespaceLogs(params)
-	emitFilteredLogs(gctx, logsList, err)
+	page, err := controller.logsProvider.FilterNamespaceLogs(gctx.Request.Context(), params)
+	controller.emitFilteredLogs(gctx, page, err)
 }
 
 func (controller *LogsController) FilterContainerLogs(gctx *gin.Context) {
-	params := initializeQueryParameters(gctx)
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
 	params.Namespace = gctx.Params.ByName("namespace")
 	params.ContainerName = gctx.Params.ByName("containername")
 	params.Podname = gctx.Params.ByName("podname")
 	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
-	logsList, err := controller.logsProvider.FilterContainerLogs(params)
-	emitFilteredLogs(gctx, logsList, err)
+	page, err := controller.logsProvider.FilterContainerLogs(gctx.Request.Context(), params)
+	controller.emitFilteredLogs(gctx, page, err)
 }
 
 func (controller *LogsController) FilterLabelLogs(gctx *gin.Context) {
-	params := initializeQueryParameters(gctx)
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
 	labels := gctx.Params.ByName("labels")
 	labelsList := strings.Split(labels, ",") //split labels on "," to obtain a list of individual labels
 	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
-	logsList, err := controller.logsProvider.FilterLabelLogs(params, labelsList)
-	emitFilteredLogs(gctx, logsList, err)
+	page, err := controller.logsProvider.FilterLabelLogs(gctx.Request.Context(), params, labelsList)
+	controller.emitFilteredLogs(gctx, page, err)
 }
 
 func (controller *LogsController) FilterLogs(gctx *gin.Context) {
-	params := initializeQueryParameters(gctx)
+	if wantsNDJSON(gctx) {
+		controller.ExportLogs(gctx)
+		return
+	}
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
 	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
-	logsList, err := controller.logsProvider.FilterLogs(params)
-	emitFilteredLogs(gctx, logsList, err)
+	page, err := controller.logsProvider.FilterLogs(gctx.Request.Context(), params)
+	controller.emitFilteredLogs(gctx, page, err)
 }
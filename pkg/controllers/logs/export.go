@@ -0,0 +1,83 @@
+package logscontroller
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// wantsNDJSON tells whether the client asked for newline delimited JSON.
+func wantsNDJSON(gctx *gin.Context) bool {
+	return strings.Contains(gctx.GetHeader("Accept"), ndjsonContentType)
+}
+
+// ExportLogs streams every log matching the filter parameters as newline
+// delimited JSON, or plain text when requested, one log per line, flushing
+// after every batch. Unlike FilterLogs the result is not limited by "maxlogs"
+// and never held in memory as a whole. The export stops as soon as the client
+// disconnects.
+func (controller *LogsController) ExportLogs(gctx *gin.Context) {
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
+	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
+
+	contentType := ndjsonContentType
+	text := wantsText(gctx)
+	if text {
+		contentType = textContentType
+	}
+	ctx := gctx.Request.Context()
+	started := false
+	start := func() {
+		gctx.Header("Content-Type", contentType)
+		gctx.Header("X-Accel-Buffering", "no") // keep nginx based routers from buffering the stream
+		gctx.Status(http.StatusOK)
+		started = true
+	}
+	err := controller.logsProvider.Export(ctx, params, func(entries []logs.LogEntry) error {
+		if !started {
+			start()
+		}
+		for _, entry := range entries {
+			if text {
+				if err := controller.textFormatter.Format(gctx.Writer, entry); err != nil {
+					return err
+				}
+				continue
+			}
+			line := entry.Hit
+			if gctx.GetInt(apiVersionKey) == 2 {
+				encoded, _ := json.Marshal(entry)
+				line = string(encoded)
+			}
+			if _, err := io.WriteString(gctx.Writer, line+"\n"); err != nil {
+				return err
+			}
+		}
+		gctx.Writer.Flush()
+		return ctx.Err()
+	})
+	if err == nil {
+		if !started {
+			// no log matches, which is an empty stream
+			start()
+			gctx.Writer.WriteHeaderNow()
+		}
+		return
+	}
+	if started || ctx.Err() != nil {
+		// the status line is gone already, all that is left is to stop writing
+		controller.log.Error("export interrupted", zap.Error(err))
+		return
+	}
+	controller.emitError(gctx, err)
+}
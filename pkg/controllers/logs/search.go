@@ -0,0 +1,33 @@
+package logscontroller
+
+import (
+	"encoding/json"
+
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/gin-gonic/gin"
+)
+
+// Search returns the logs matching the filter tree of the body, a
+// logs.SearchRequest, along with the filter parameters of the query string,
+// which also take the page size, cursor and order.
+func (controller *LogsController) Search(gctx *gin.Context) {
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
+	var request logs.SearchRequest
+	decoder := json.NewDecoder(gctx.Request.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil {
+		controller.emitError(gctx, logs.InvalidSearch("body", "a JSON object with a filter is required: "+err.Error()))
+		return
+	}
+	if request.Filter == nil {
+		controller.emitError(gctx, logs.InvalidSearch("filter", "a condition is required"))
+		return
+	}
+	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
+	page, err := controller.logsProvider.Search(gctx.Request.Context(), params, *request.Filter)
+	controller.emitFilteredLogs(gctx, page, err)
+}
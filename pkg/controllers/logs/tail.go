@@ -0,0 +1,125 @@
+package logscontroller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTailInterval      = 2 * time.Second
+	defaultHeartbeatInterval = 15 * time.Second
+)
+
+// TailLogs keeps the connection open and streams log entries matching the
+// filter parameters as Server-Sent Events as soon as they are indexed. It
+// starts from "starttime" or "since" when given and from the current time
+// otherwise, and resumes from the Last-Event-ID header when a client reconnects.
+// When "finishtime" is given, the stream ends with an "end" event once every
+// entry up to it has been sent.
+func (controller *LogsController) TailLogs(gctx *gin.Context) {
+	params := controller.initializeQueryParameters(gctx)
+	if gctx.IsAborted() {
+		return
+	}
+	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
+	params.Order = "asc"
+
+	now := time.Now().UTC()
+	timeRange, err := params.TimeRange(now)
+	if err != nil {
+		controller.emitError(gctx, err)
+		return
+	}
+	cursor := logs.Cursor{Timestamp: now}.Encode()
+	if !timeRange.Start.IsZero() {
+		cursor = logs.Cursor{Timestamp: timeRange.Start}.Encode()
+	}
+	if lastEventID := gctx.GetHeader("Last-Event-ID"); len(lastEventID) > 0 {
+		if _, err := logs.ParseCursor(lastEventID); err != nil {
+			controller.emitError(gctx, err)
+			return
+		}
+		cursor = lastEventID
+	}
+
+	gctx.Header("Content-Type", "text/event-stream")
+	gctx.Header("Cache-Control", "no-cache")
+	gctx.Header("Connection", "keep-alive")
+	gctx.Header("X-Accel-Buffering", "no") // keep nginx based routers from buffering the stream
+	gctx.Status(http.StatusOK)
+	gctx.Writer.Flush()
+
+	ctx := gctx.Request.Context()
+	poll := time.NewTicker(controller.tailInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(controller.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		params.Cursor = cursor
+		polled := time.Now()
+		page, err := controller.logsProvider.FilterLogs(ctx, params)
+		if ctx.Err() != nil {
+			return // the client went away
+		}
+		if err != nil && logs.KindOf(err) != logs.KindNotFound {
+			controller.log.Error("failed to poll logs for tail", zap.Error(err))
+			// like emitError, the client only gets the details meant for it
+			writeEvent(gctx, "error", "", logs.NewProblem(err).Detail)
+			return
+		}
+		if len(page.EndCursor) > 0 {
+			for i, entry := range page.Logs {
+				id := ""
+				if i == len(page.Logs)-1 {
+					id = page.EndCursor
+				}
+				data := entry.Hit
+				if gctx.GetInt(apiVersionKey) == 2 {
+					encoded, _ := json.Marshal(entry)
+					data = string(encoded)
+				}
+				writeEvent(gctx, "log", id, data)
+			}
+			cursor = page.EndCursor
+		}
+		if len(page.NextCursor) > 0 {
+			continue // more entries are already waiting, fetch them right away
+		}
+		if !timeRange.Finish.IsZero() && !polled.Before(timeRange.Finish) {
+			// the poll started past the finish time, nothing is left to send
+			writeEvent(gctx, "end", "", "")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(gctx.Writer, ": heartbeat\n\n")
+			gctx.Writer.Flush()
+		case <-poll.C:
+		}
+	}
+}
+
+// writeEvent writes a single Server-Sent Event and flushes it to the client.
+// The id, when set, lets a reconnecting client resume after this event.
+func writeEvent(gctx *gin.Context, event string, id string, data string) {
+	if len(id) > 0 {
+		fmt.Fprintf(gctx.Writer, "id: %s\n", id)
+	}
+	fmt.Fprintf(gctx.Writer, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(gctx.Writer, "data: %s\n", line)
+	}
+	fmt.Fprint(gctx.Writer, "\n")
+	gctx.Writer.Flush()
+}
@@ -0,0 +1,38 @@
+package logscontroller
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const textContentType = "text/plain; charset=utf-8"
+
+// wantsText tells whether the client asked for plain text with "format=text"
+// or an Accept header preferring text/plain.
+func wantsText(gctx *gin.Context) bool {
+	if format, ok := gctx.GetQuery("format"); ok {
+		return format == "text"
+	}
+	return strings.Contains(gctx.GetHeader("Accept"), "text/plain")
+}
+
+// emitText prints one line per log entry. The cursor of the next page, if
+// any, is returned in the X-Next-Cursor header.
+func (controller *LogsController) emitText(gctx *gin.Context, page logs.Page) {
+	if len(page.NextCursor) > 0 {
+		gctx.Header("X-Next-Cursor", page.NextCursor)
+	}
+	gctx.Header("Content-Type", textContentType)
+	gctx.Status(http.StatusOK)
+	for _, entry := range page.Logs {
+		err := controller.textFormatter.Format(gctx.Writer, entry)
+		if err != nil {
+			controller.log.Error("failed to format log entry", zap.Error(err))
+			return
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package lokicontroller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/authorization"
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/ViaQ/log-exploration-api/pkg/middleware"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit        = 100
+	defaultQueryRange   = time.Hour
+	defaultLabelsRange  = 6 * time.Hour
+	defaultTailInterval = 2 * time.Second
+)
+
+// LokiController serves a subset of the Loki HTTP API on top of the logs
+// provider, so that Grafana's Loki datasource and other Loki clients can
+// query the logs.
+type LokiController struct {
+	logsProvider logs.LogsProvider
+	log          *zap.Logger
+	tailInterval time.Duration
+}
+
+// Stream is a set of log lines sharing the same labels.
+type Stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// QueryResponse is the response of query_range for log queries.
+type QueryResponse struct {
+	Status string    `json:"status"`
+	Data   QueryData `json:"data"`
+}
+
+type QueryData struct {
+	ResultType string   `json:"resultType"`
+	Result     []Stream `json:"result"`
+}
+
+// LabelsResponse is the response of the labels and label values endpoints.
+type LabelsResponse struct {
+	Status string   `json:"status"`
+	Data   []string `json:"data"`
+}
+
+// TailResponse is a message sent on a tail connection.
+type TailResponse struct {
+	Streams        []Stream      `json:"streams"`
+	DroppedEntries []interface{} `json:"dropped_entries"`
+}
+
+func NewLokiController(log *zap.Logger, logsProvider logs.LogsProvider, authorizer *authorization.Authorizer, router *gin.Engine) *LokiController {
+	controller := &LokiController{
+		log:          log,
+		logsProvider: logsProvider,
+		tailInterval: defaultTailInterval,
+	}
+
+	r := router.Group("loki/api/v1")
+	r.Use(middleware.TokenHeader())
+	if authorizer != nil {
+		r.Use(middleware.Authorization(authorizer))
+	}
+	r.GET("/query_range", controller.QueryRange)
+	r.GET("/labels", controller.Labels)
+	r.GET("/label/:name/values", controller.LabelValues)
+	r.GET("/tail", controller.Tail)
+	return controller
+}
+
+// QueryRange returns the log lines matching a LogQL query between start and
+// end, newest first unless direction is forward.
+func (controller *LokiController) QueryRange(gctx *gin.Context) {
+	params, err := queryParameters(gctx, defaultQueryRange)
+	if err != nil {
+		gctx.String(http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+	limit := defaultLimit
+	if value := gctx.Query("limit"); len(value) > 0 {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			gctx.String(http.StatusBadRequest, "invalid limit: a positive integer is required")
+			return
+		}
+	}
+	params.MaxLogs = strconv.Itoa(limit)
+	switch gctx.DefaultQuery("direction", "backward") {
+	case "backward":
+		params.Order = "desc"
+	case "forward":
+		params.Order = "asc"
+	default:
+		gctx.String(http.StatusBadRequest, "invalid direction: backward or forward is required")
+		return
+	}
+
+	page, err := controller.logsProvider.FilterLogs(gctx.Request.Context(), params)
+	if err != nil && logs.KindOf(err) != logs.KindNotFound {
+		controller.emitError(gctx, err)
+		return
+	}
+	gctx.JSON(http.StatusOK, &QueryResponse{
+		Status: "success",
+		Data:   QueryData{ResultType: "streams", Result: streams(page.Logs)},
+	})
+}
+
+// Labels returns the names of the stream labels.
+func (controller *LokiController) Labels(gctx *gin.Context) {
+	gctx.JSON(http.StatusOK, &LabelsResponse{Status: "success", Data: labelNames})
+}
+
+// LabelValues returns the values of a stream label between start and end.
+func (controller *LokiController) LabelValues(gctx *gin.Context) {
+	name := gctx.Param("name")
+	if name == logTypeLabel {
+		gctx.JSON(http.StatusOK, &LabelsResponse{Status: "success", Data: []string{"application", "audit", "infrastructure"}})
+		return
+	}
+	field := map[string]string{
+		namespaceLabel: "namespace",
+		podLabel:       "pod",
+		containerLabel: "container",
+		levelLabel:     "level",
+	}[name]
+	if len(field) == 0 {
+		gctx.JSON(http.StatusOK, &LabelsResponse{Status: "success", Data: []string{}})
+		return
+	}
+	params, err := queryParameters(gctx, defaultLabelsRange)
+	if err != nil {
+		gctx.String(http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	values, err := controller.logsProvider.Values(gctx.Request.Context(), params, field, logs.ValuesOptions{Size: logs.MaxValuesSize})
+	if err != nil && logs.KindOf(err) != logs.KindNotFound {
+		controller.emitError(gctx, err)
+		return
+	}
+	data := []string{}
+	for _, value := range values.Values {
+		data = append(data, value.Value)
+	}
+	gctx.JSON(http.StatusOK, &LabelsResponse{Status: "success", Data: data})
+}
+
+// Tail upgrades the connection to a WebSocket and sends the log lines matching
+// a LogQL query from start on as they are indexed, until the client goes away.
+func (controller *LokiController) Tail(gctx *gin.Context) {
+	params, err := queryParameters(gctx, defaultQueryRange)
+	if err != nil {
+		gctx.String(http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+	params.FinishTime = ""
+	params.Order = "asc"
+	params.MaxLogs = strconv.Itoa(defaultLimit)
+	if value := gctx.Query("limit"); len(value) > 0 {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			gctx.String(http.StatusBadRequest, "invalid limit: a positive integer is required")
+			return
+		}
+		params.MaxLogs = value
+	}
+	startTime, _ := time.Parse(time.RFC3339Nano, params.StartTime)
+	params.StartTime = ""
+	cursor := logs.Cursor{Timestamp: startTime}.Encode()
+
+	conn, err := upgradeWebsocket(gctx.Writer, gctx.Request)
+	if err != nil {
+		return // the upgrader answered the client
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(gctx.Request.Context())
+	defer cancel()
+	go func() {
+		conn.WaitClose()
+		cancel()
+	}()
+
+	poll := time.NewTicker(controller.tailInterval)
+	defer poll.Stop()
+	for {
+		params.Cursor = cursor
+		page, err := controller.logsProvider.FilterLogs(ctx, params)
+		if ctx.Err() != nil {
+			return // the client went away
+		}
+		if err != nil && logs.KindOf(err) != logs.KindNotFound {
+			controller.log.Error("failed to poll logs for tail", zap.Error(err))
+			return
+		}
+		if len(page.Logs) > 0 {
+			message, _ := json.Marshal(&TailResponse{Streams: streams(page.Logs)})
+			if err := conn.WriteText(message); err != nil {
+				return
+			}
+			cursor = page.EndCursor
+		}
+		if len(page.NextCursor) > 0 {
+			continue // more entries are already waiting, fetch them right away
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		}
+	}
+}
+
+// emitError answers like Loki does, with the status code of the error and its
+// message as plain text. Unexpected errors are logged, as the client is only
+// told that an internal error occurred.
+func (controller *LokiController) emitError(gctx *gin.Context, err error) {
+	problem := logs.NewProblem(err)
+	if problem.Status >= http.StatusInternalServerError {
+		controller.log.Error("failed to query logs", zap.Error(err))
+	}
+	gctx.String(problem.Status, "%s", problem.Detail)
+}
+
+// queryParameters reads the LogQL query and the time range, which defaults to
+// the given duration before end.
+func queryParameters(gctx *gin.Context, defaultRange time.Duration) (logs.Parameters, error) {
+	var params logs.Parameters
+	if query := gctx.Query("query"); len(query) > 0 {
+		err := parseLogQL(query, &params)
+		if err != nil {
+			return logs.Parameters{}, err
+		}
+	}
+	end := time.Now()
+	if value := gctx.Query("end"); len(value) > 0 {
+		var err error
+		end, err = parseTime(value)
+		if err != nil {
+			return logs.Parameters{}, err
+		}
+	}
+	start := end.Add(-defaultRange)
+	if value := gctx.Query("start"); len(value) > 0 {
+		var err error
+		start, err = parseTime(value)
+		if err != nil {
+			return logs.Parameters{}, err
+		}
+	}
+	params.StartTime = start.UTC().Format(time.RFC3339Nano)
+	params.FinishTime = end.UTC().Format(time.RFC3339Nano)
+	params.Namespaces = middleware.AllowedNamespaces(gctx)
+	params.Indices = middleware.AllowedIndices(gctx)
+	params.Token = map[string]string{"Authorization": gctx.Request.Header["Authorization"][0]}
+	return params, nil
+}
+
+// parseTime accepts the timestamps Loki does: seconds since the epoch, with a
+// fraction or up to 10 digits, nanoseconds since the epoch, or RFC3339.
+func parseTime(value string) (time.Time, error) {
+	if epoch, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if len(value) <= 10 {
+			return time.Unix(epoch, 0), nil
+		}
+		return time.Unix(0, epoch), nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, logs.InvalidTimeStamp()
+	}
+	return timestamp, nil
+}
+
+// streams groups the entries by stream labels, keeping their order.
+func streams(entries []logs.LogEntry) []Stream {
+	result := []Stream{}
+	positions := map[string]int{}
+	for _, entry := range entries {
+		labels := streamLabels(entry)
+		key, _ := json.Marshal(labels) // map keys are sorted, so equal label sets give equal keys
+		position, ok := positions[string(key)]
+		if !ok {
+			position = len(result)
+			positions[string(key)] = position
+			result = append(result, Stream{Stream: labels})
+		}
+		value := [2]string{strconv.FormatInt(entry.Timestamp.UnixNano(), 10), entry.Message}
+		result[position].Values = append(result[position].Values, value)
+	}
+	return result
+}
+
+func streamLabels(entry logs.LogEntry) map[string]string {
+	labels := map[string]string{}
+	if entry.Kubernetes != nil {
+		setIfPresent(labels, namespaceLabel, entry.Kubernetes.NamespaceName)
+		setIfPresent(labels, podLabel, entry.Kubernetes.PodName)
+		setIfPresent(labels, containerLabel, entry.Kubernetes.ContainerName)
+	}
+	setIfPresent(labels, levelLabel, entry.Level)
+	for logType, index := range logTypes {
+		if strings.HasPrefix(entry.Index, index) {
+			labels[logTypeLabel] = logType
+		}
+	}
+	return labels
+}
+
+func setIfPresent(labels map[string]string, name string, value string) {
+	if len(value) > 0 {
+		labels[name] = value
+	}
+}
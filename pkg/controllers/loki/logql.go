@@ -0,0 +1,200 @@
+package lokicontroller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+)
+
+// Stream labels, named like the labels of the OpenShift Logging LokiStack.
+const (
+	namespaceLabel = "kubernetes_namespace_name"
+	podLabel       = "kubernetes_pod_name"
+	containerLabel = "kubernetes_container_name"
+	levelLabel     = "level"
+	logTypeLabel   = "log_type"
+)
+
+// labelNames are the stream labels that can be selected.
+var labelNames = []string{containerLabel, namespaceLabel, podLabel, levelLabel, logTypeLabel}
+
+// logTypes maps the log_type label values to the index names.
+var logTypes = map[string]string{
+	"application":    "app",
+	"infrastructure": "infra",
+	"audit":          "audit",
+}
+
+// parseLogQL translates a LogQL log query into filter parameters. Only a
+// subset is supported: a stream selector with equality matchers on the stream
+// labels, followed by any number of |= and != line filters, such as
+//
+//	{kubernetes_namespace_name="openshift-monitoring", level="error"} |= "timeout" != "retrying"
+//
+// Line filters match phrases of the message rather than arbitrary substrings.
+func parseLogQL(query string, params *logs.Parameters) error {
+	p := &logQLParser{input: query}
+	p.skipSpaces()
+	if !p.consume("{") {
+		return p.errorf("a stream selector starting with { is required")
+	}
+	for {
+		p.skipSpaces()
+		if p.consume("}") {
+			break
+		}
+		label := p.identifier()
+		if len(label) == 0 {
+			return p.errorf("a label name is required")
+		}
+		p.skipSpaces()
+		switch {
+		case strings.HasPrefix(p.rest(), "=~"), strings.HasPrefix(p.rest(), "!"):
+			return p.errorf("only = matchers are supported")
+		case !p.consume("="):
+			return p.errorf("a matcher is required after " + label)
+		}
+		p.skipSpaces()
+		value, err := p.quoted()
+		if err != nil {
+			return err
+		}
+		err = setLabel(params, label, value)
+		if err != nil {
+			return err
+		}
+		p.skipSpaces()
+		if !p.consume(",") && !strings.HasPrefix(p.rest(), "}") {
+			return p.errorf("expected , or }")
+		}
+	}
+
+	var terms []string
+	for {
+		p.skipSpaces()
+		if len(p.rest()) == 0 {
+			break
+		}
+		negate := false
+		switch {
+		case strings.HasPrefix(p.rest(), "|~"), strings.HasPrefix(p.rest(), "!~"):
+			return p.errorf("regular expression line filters are not supported")
+		case p.consume("|="):
+		case p.consume("!="):
+			negate = true
+		default:
+			return p.errorf("only |= and != line filters are supported")
+		}
+		p.skipSpaces()
+		text, err := p.quoted()
+		if err != nil {
+			return err
+		}
+		if strings.Contains(text, `"`) {
+			return p.errorf("line filters cannot contain double quotes")
+		}
+		if len(strings.TrimSpace(text)) == 0 {
+			continue // matches every line
+		}
+		term := `"` + text + `"`
+		if negate {
+			term = "-" + term
+		}
+		terms = append(terms, term)
+	}
+	params.Query = strings.Join(terms, " ")
+	return nil
+}
+
+// setLabel sets the filter parameter matching a stream label.
+func setLabel(params *logs.Parameters, label string, value string) error {
+	// the filter parameters read them as wildcards and lists of values, while
+	// an equality matcher compares the value as it is
+	if strings.ContainsAny(value, "*?,") {
+		return errors.New("parse error: the value of " + label + " cannot contain *, ? or , as = matches it exactly")
+	}
+	switch label {
+	case namespaceLabel:
+		params.Namespace = value
+	case podLabel:
+		params.Podname = value
+	case containerLabel:
+		params.ContainerName = value
+	case levelLabel:
+		params.Level = value
+	case logTypeLabel:
+		index, ok := logTypes[value]
+		if !ok {
+			return errors.New("parse error: log_type must be application, infrastructure or audit")
+		}
+		params.Index = index
+	default:
+		return errors.New("parse error: unknown label " + label + ", the labels are " + strings.Join(labelNames, ", "))
+	}
+	return nil
+}
+
+type logQLParser struct {
+	input    string
+	position int
+}
+
+func (p *logQLParser) rest() string {
+	return p.input[p.position:]
+}
+
+func (p *logQLParser) errorf(message string) error {
+	return errors.New("parse error at position " + strconv.Itoa(p.position+1) + ": " + message)
+}
+
+func (p *logQLParser) skipSpaces() {
+	for p.position < len(p.input) && strings.ContainsRune(" \t\r\n", rune(p.input[p.position])) {
+		p.position++
+	}
+}
+
+func (p *logQLParser) consume(token string) bool {
+	if strings.HasPrefix(p.rest(), token) {
+		p.position += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *logQLParser) identifier() string {
+	start := p.position
+	for p.position < len(p.input) {
+		c := p.input[p.position]
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(p.position > start && c >= '0' && c <= '9') {
+			break
+		}
+		p.position++
+	}
+	return p.input[start:p.position]
+}
+
+// quoted reads a double quoted or backquoted string with Go escapes, like LogQL.
+func (p *logQLParser) quoted() (string, error) {
+	rest := p.rest()
+	if len(rest) == 0 || (rest[0] != '"' && rest[0] != '`') {
+		return "", p.errorf("a quoted string is required")
+	}
+	end := 1
+	for end < len(rest) && rest[end] != rest[0] {
+		if rest[0] == '"' && rest[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(rest) {
+		return "", p.errorf("unterminated string")
+	}
+	value, err := strconv.Unquote(rest[:end+1])
+	if err != nil {
+		return "", p.errorf("invalid string " + rest[:end+1])
+	}
+	p.position += end + 1
+	return value, nil
+}
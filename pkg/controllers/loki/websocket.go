@@ -0,0 +1,63 @@
+package lokicontroller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxMessageSize caps the messages read from the client, which only ever
+// closes the connection or pings. Larger messages close the connection with
+// the message too big status.
+const maxMessageSize = 4096
+
+// closeTimeout bounds the time spent sending the close message.
+const closeTimeout = time.Second
+
+var upgrader = websocket.Upgrader{
+	// like Loki, any origin is accepted: requests are authorized by their
+	// bearer token, which browsers do not send on their own
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// websocketConn is the server side of a WebSocket connection, which is all
+// the Loki tail API needs: the server sends text messages and the client
+// only ever closes the connection or pings.
+type websocketConn struct {
+	conn *websocket.Conn
+}
+
+// upgradeWebsocket answers the opening handshake and takes over the
+// connection. When the handshake fails, the client has been answered already.
+func upgradeWebsocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	// the timeouts of the server apply to requests, not to the connection taken over
+	_ = conn.UnderlyingConn().SetDeadline(time.Time{})
+	conn.SetReadLimit(maxMessageSize)
+	return &websocketConn{conn: conn}, nil
+}
+
+// WriteText sends a text message. It must not be called concurrently.
+func (c *websocketConn) WriteText(data []byte) error {
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// WaitClose reads the messages sent by the client, answering pings, until the
+// client closes the connection, breaks the protocol or the connection breaks.
+func (c *websocketConn) WaitClose() {
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// Close sends a normal closure and closes the connection.
+func (c *websocketConn) Close() error {
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(closeTimeout))
+	return c.conn.Close()
+}
@@ -2,11 +2,10 @@ package elastic
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
@@ -14,19 +13,26 @@ import (
 	"github.com/ViaQ/log-exploration-api/pkg/constants"
 	"github.com/ViaQ/log-exploration-api/pkg/logs"
 	"github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
 	"go.uber.org/zap"
 )
 
 type ElasticRepository struct {
 	esClient *elasticsearch.Client
 	log      *zap.Logger
+	indices  map[string]string // the index or alias holding the logs of every index of the API
+	timeout  time.Duration     // how long a query may take, 0 for no limit
 }
 
-func (repository *ElasticRepository) CheckReadiness() bool {
-	clusterHealth, err := repository.esClient.Cluster.Health()
+func (repository *ElasticRepository) CheckReadiness(ctx context.Context) bool {
+	ctx, cancel := repository.queryContext(ctx)
+	defer cancel()
+	clusterHealth, err := repository.esClient.Cluster.Health(repository.esClient.Cluster.Health.WithContext(ctx))
 	if err != nil {
 		repository.log.Error("error while connecting to elasticsearch to retrieve health status", zap.Error(err))
+		return false
 	}
+	defer clusterHealth.Body.Close()
 
 	var result map[string]interface{}
 	err = json.NewDecoder(clusterHealth.Body).Decode(&result)
@@ -43,32 +49,35 @@ func (repository *ElasticRepository) CheckReadiness() bool {
 
 const (
 	Term          = "term"
+	Terms         = "terms"
 	Match         = "match"
+	MatchPhrase   = "match_phrase"
+	Prefix        = "prefix"
+	Regexp        = "regexp"
+	Wildcard      = "wildcard"
 	NamespaceName = "kubernetes.namespace_name"
 	PodName       = "kubernetes.pod_name"
 	ContainerName = "kubernetes.container_name.raw"
 	FlatLabel     = "kubernetes.flat_labels"
+	Message       = "message"
 )
 
-func CreateElasticConfig(config *configuration.ElasticsearchConfig) (*elasticsearch.Client, error) {
+func CreateElasticConfig(log *zap.Logger, config *configuration.ElasticsearchConfig) (*elasticsearch.Client, error) {
 	cfg := elasticsearch.Config{
 		Addresses: []string{
 			config.EsAddress,
 		},
 	}
 
-	if config.UseTLS {
-		cert, err := tls.LoadX509KeyPair(config.EsCert, config.EsKey)
-		if err != nil {
-			return nil, err
-		}
-		cfg.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-				Certificates:       []tls.Certificate{cert},
-			},
-		}
+	dialer := &net.Dialer{Timeout: config.DialTimeout, KeepAlive: 30 * time.Second}
+	dialTLS, err := newTLSDialer(log, config, dialer)
+	if err != nil {
+		return nil, err
 	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.DialTLSContext = dialTLS
+	cfg.Transport = transport
 
 	esClient, err := elasticsearch.NewClient(cfg)
 	if err != nil {
@@ -77,7 +86,7 @@ func CreateElasticConfig(config *configuration.ElasticsearchConfig) (*elasticsea
 	return esClient, nil
 }
 func NewElasticRepository(log *zap.Logger, config *configuration.ElasticsearchConfig) (logs.LogsProvider, error) {
-	esClient, err := CreateElasticConfig(config)
+	esClient, err := CreateElasticConfig(log, config)
 	if err != nil {
 		log.Error("failed to configure Elasticsearch", zap.Error(err))
 		return nil, err
@@ -86,68 +95,110 @@ func NewElasticRepository(log *zap.Logger, config *configuration.ElasticsearchCo
 	repository := &ElasticRepository{
 		log:      log,
 		esClient: esClient,
+		indices:  map[string]string{},
+		timeout:  config.Timeout,
+	}
+	for i, index := range config.Indices() {
+		repository.indices[logIndices[i]] = index
 	}
 	return repository, nil
 }
-func generateLogs(queryBuilder []map[string]interface{}, params logs.Parameters, repository *ElasticRepository) ([]string, error) {
-	if len(params.Index) > 0 {
-		term := map[string]interface{}{
-			"term": map[string]interface{}{
-				"_index": params.Index},
-		}
-		queryBuilder = append(queryBuilder, term)
+
+// queryContext returns the context of a query, which ends with the request it
+// is made for or when the query timeout elapses.
+func (repository *ElasticRepository) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if repository.timeout > 0 {
+		return context.WithTimeout(ctx, repository.timeout)
 	}
+	return context.WithCancel(ctx)
+}
 
-	if len(params.StartTime) > 0 && len(params.FinishTime) > 0 {
-		startTime, _ := time.Parse(time.RFC3339Nano, params.StartTime)
+// generateLogs searches the logs matching the clauses of a method, which
+// replace the filters on the namespace, pod and container, and the other
+// filter parameters.
+func generateLogs(ctx context.Context, queryBuilder []map[string]interface{}, params logs.Parameters, repository *ElasticRepository) (logs.Page, error) {
+	params.Namespace, params.Podname, params.ContainerName = "", "", ""
+	return repository.searchLogs(ctx, params, queryBuilder...)
+}
 
-		finishTime, _ := time.Parse(time.RFC3339Nano, params.FinishTime)
+// searchLogs returns the page of the logs matching the clauses and the filter
+// parameters described by the cursor and maxlogs parameters.
+func (repository *ElasticRepository) searchLogs(ctx context.Context, params logs.Parameters, clauses ...map[string]interface{}) (logs.Page, error) {
+	indices, params := repository.searchIndices(params)
+	maxEntries := params.PageSize()
+	query := map[string]interface{}{
+		"query": newFilterQuery(params, clauses...).source(),
+		"size":  maxEntries + 1, // one extra hit tells whether there is a next page
+		"sort":  sortQuery(params.Order),
+	}
+	if source := sourceFilter(params.Projection()); source != nil {
+		query["_source"] = source
+	}
+	if len(params.Cursor) > 0 {
+		cursor, _ := logs.ParseCursor(params.Cursor)
+		query["search_after"] = []interface{}{cursor.Timestamp.UnixNano() / int64(time.Millisecond), cursor.Index, cursor.ID}
+	}
+	return repository.getLogsList(ctx, params.Token, query, maxEntries, indices)
+}
 
-		timeSubQuery := map[string]interface{}{
-			"range": map[string]interface{}{
-				"@timestamp": map[string]interface{}{
-					"gte": startTime,
-					"lte": finishTime,
-				},
-			},
+// logIndices are the indices of the API, in the order of configuration.Indices.
+var logIndices = []string{constants.InfraIndexName, constants.AppIndexName, constants.AuditIndexName}
+
+// searchIndices returns the indices or aliases to search and the parameters
+// the query is built from. An index the caller may read is searched alone
+// rather than filtered on. Otherwise every index visible to the caller is
+// searched, and the visible indices are also filtered on so that the search
+// matches nothing when none of them is.
+func (repository *ElasticRepository) searchIndices(params logs.Parameters) ([]string, logs.Parameters) {
+	var visible []string
+	for _, index := range logIndices {
+		if params.Indices == nil || containsString(params.Indices, index) {
+			visible = append(visible, repository.indices[index])
 		}
-		queryBuilder = append(queryBuilder, timeSubQuery)
 	}
-	if len(params.Level) > 0 {
-		term := map[string]interface{}{
-			"term": map[string]interface{}{
-				"level": params.Level},
+	if params.Indices != nil {
+		params.Indices = visible
+		if len(visible) == 0 {
+			params.Indices = []string{}
 		}
-		queryBuilder = append(queryBuilder, term)
 	}
-	maxEntries := 1000
-	if len(params.MaxLogs) > 0 {
-		maxLogs, _ := strconv.Atoi(params.MaxLogs)
-
-		maxEntries = maxLogs
-
+	for _, index := range logIndices {
+		alias := repository.indices[index]
+		if (params.Index == index || params.Index == alias) && containsString(visible, alias) {
+			params.Index = ""
+			return []string{alias}, params
+		}
 	}
+	if len(visible) == 0 {
+		for _, index := range logIndices {
+			visible = append(visible, repository.indices[index])
+		}
+	}
+	return visible, params
+}
 
-	var sortQuery []map[string]interface{}
-	sortSubQuery := map[string]interface{}{
-		"@timestamp": map[string]interface{}{
-			"order": "desc"},
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
 	}
-	sortQuery = append(sortQuery, sortSubQuery)
+	return false
+}
 
-	query := map[string]interface{}{
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must": queryBuilder,
-			}},
-		"size": maxEntries,
-		"sort": sortQuery,
-	}
-	logsList, err := getLogsList(params.Token, query, repository.esClient, repository.log)
-	if err != nil {
-		return nil, err
+// sortQuery orders hits newest first unless ascending order is requested. The
+// index and then the document id, which is only unique within an index, break
+// ties between hits sharing a timestamp so that search_after can resume from
+// any hit.
+func sortQuery(order string) []map[string]interface{} {
+	if order != "asc" {
+		order = "desc"
+	}
+	return []map[string]interface{}{
+		{"@timestamp": map[string]interface{}{"order": order}},
+		{"_index": map[string]interface{}{"order": "asc"}},
+		{"_id": map[string]interface{}{"order": "asc"}},
 	}
-	return logsList, nil
 }
 
 func appendToQueryBuilder(key string, typeOfQuery string, value interface{}) map[string]interface{} {
@@ -157,34 +208,34 @@ func appendToQueryBuilder(key string, typeOfQuery string, value interface{}) map
 	}
 	return query
 }
-func (repository *ElasticRepository) FilterPodLogs(params logs.Parameters) ([]string, error) {
-	err := validateParams(params)
+func (repository *ElasticRepository) FilterPodLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	err := params.Validate()
 	if err != nil {
 		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
-		return nil, err
+		return logs.Page{}, err
 	}
 	var queryBuilder []map[string]interface{}
 	queryBuilder = append(queryBuilder, appendToQueryBuilder(NamespaceName, Term, params.Namespace))
 	queryBuilder = append(queryBuilder, appendToQueryBuilder(PodName, Term, params.Podname))
-	return generateLogs(queryBuilder, params, repository)
+	return generateLogs(ctx, queryBuilder, params, repository)
 }
 
-func (repository *ElasticRepository) FilterNamespaceLogs(params logs.Parameters) ([]string, error) {
-	err := validateParams(params)
+func (repository *ElasticRepository) FilterNamespaceLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	err := params.Validate()
 	if err != nil {
 		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
-		return nil, err
+		return logs.Page{}, err
 	}
 	var queryBuilder []map[string]interface{}
 	queryBuilder = append(queryBuilder, appendToQueryBuilder(NamespaceName, Term, params.Namespace))
-	return generateLogs(queryBuilder, params, repository)
+	return generateLogs(ctx, queryBuilder, params, repository)
 }
 
-func (repository *ElasticRepository) FilterLabelLogs(params logs.Parameters, labelsList []string) ([]string, error) {
-	err := validateParams(params)
+func (repository *ElasticRepository) FilterLabelLogs(ctx context.Context, params logs.Parameters, labelsList []string) (logs.Page, error) {
+	err := params.Validate()
 	if err != nil {
 		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
-		return nil, err
+		return logs.Page{}, err
 	}
 	var queryBuilder []map[string]interface{}
 	for _, label := range labelsList {
@@ -192,117 +243,455 @@ func (repository *ElasticRepository) FilterLabelLogs(params logs.Parameters, lab
 			"query": label, "operator": "AND"}
 		queryBuilder = append(queryBuilder, appendToQueryBuilder(FlatLabel, Match, value))
 	}
-	return generateLogs(queryBuilder, params, repository)
+	return generateLogs(ctx, queryBuilder, params, repository)
 }
 
-func (repository *ElasticRepository) FilterContainerLogs(params logs.Parameters) ([]string, error) {
-	err := validateParams(params)
+func (repository *ElasticRepository) FilterEntityLogs(ctx context.Context, params logs.Parameters, kind string, name string) (logs.Page, error) {
+	err := params.Validate()
 	if err != nil {
 		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
-		return nil, err
+		return logs.Page{}, err
+	}
+	selector, err := logs.NewWorkloadSelector(kind, name)
+	if err != nil {
+		repository.log.Error("Invalid entity:", zap.Error(err))
+		return logs.Page{}, err
+	}
+	var queryBuilder []map[string]interface{}
+	queryBuilder = append(queryBuilder, appendToQueryBuilder(NamespaceName, Term, params.Namespace))
+	queryBuilder = append(queryBuilder, appendToQueryBuilder(PodName, Regexp, selector.PodNamePattern))
+	if selector.LabelPrefix {
+		queryBuilder = append(queryBuilder, appendToQueryBuilder(FlatLabel, Prefix, selector.Label))
+	} else {
+		queryBuilder = append(queryBuilder, appendToQueryBuilder(FlatLabel, Term, selector.Label))
+	}
+	return generateLogs(ctx, queryBuilder, params, repository)
+}
+
+func (repository *ElasticRepository) FilterContainerLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	err := params.Validate()
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.Page{}, err
 	}
 	var queryBuilder []map[string]interface{}
 	queryBuilder = append(queryBuilder, appendToQueryBuilder(NamespaceName, Term, params.Namespace))
 	queryBuilder = append(queryBuilder, appendToQueryBuilder(PodName, Term, params.Podname))
 	queryBuilder = append(queryBuilder, appendToQueryBuilder(ContainerName, Term, params.ContainerName))
-	return generateLogs(queryBuilder, params, repository)
+	return generateLogs(ctx, queryBuilder, params, repository)
 }
-func (repository *ElasticRepository) Logs(params logs.Parameters) ([]string, error) {
+func (repository *ElasticRepository) Logs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
 
-	err := validateParams(params)
+	err := params.Validate()
 	if err != nil {
 		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
-		return nil, err
+		return logs.Page{}, err
 	}
 	var queryBuilder []map[string]interface{}
-	return generateLogs(queryBuilder, params, repository)
+	return generateLogs(ctx, queryBuilder, params, repository)
 
 }
 
-func (repository *ElasticRepository) FilterLogs(params logs.Parameters) ([]string, error) {
+func (repository *ElasticRepository) FilterLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
 
-	err := validateParams(params)
+	err := params.Validate()
 
 	if err != nil {
 		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
-		return nil, err
+		return logs.Page{}, err
 	}
 
-	var queryBuilder []map[string]interface{}
+	return repository.searchLogs(ctx, params)
+}
 
-	if len(params.Namespace) > 0 {
-		namespaceSubQuery := map[string]interface{}{
-			"term": map[string]interface{}{
-				"kubernetes.namespace_name": params.Namespace},
+func (repository *ElasticRepository) Search(ctx context.Context, params logs.Parameters, filter logs.Condition) (logs.Page, error) {
+	err := params.Validate()
+	if err == nil {
+		err = filter.Validate()
+	}
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.Page{}, err
+	}
+	return repository.searchLogs(ctx, params, conditionQuery(filter, time.Now()))
+}
+
+// Histogram counts the logs matching the filter parameters of FilterLogs per
+// time bucket with a date_histogram, or an auto_date_histogram when no interval is given.
+func (repository *ElasticRepository) Histogram(ctx context.Context, params logs.Parameters, options logs.HistogramOptions) (logs.Histogram, error) {
+	err := params.Validate()
+	if err == nil {
+		err = options.Validate()
+	}
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.Histogram{}, err
+	}
+	indices, params := repository.searchIndices(params)
+
+	var histogram map[string]interface{}
+	if len(options.Interval) > 0 {
+		dateHistogram := map[string]interface{}{
+			"field":          "@timestamp",
+			"fixed_interval": options.Interval,
+			"min_doc_count":  0,
+		}
+		timeRange, _ := params.TimeRange(time.Now())
+		width, _ := logs.ParseInterval(options.Interval)
+		if err := logs.CheckBuckets(width, timeRange.Start, timeRange.Finish); err != nil {
+			return logs.Histogram{}, err
 		}
-		queryBuilder = append(queryBuilder, namespaceSubQuery)
+		if bounds := rangeBounds(timeRange, "min", "max"); len(bounds) > 0 {
+			dateHistogram["extended_bounds"] = bounds
+		}
+		histogram = map[string]interface{}{"date_histogram": dateHistogram}
+	} else {
+		histogram = map[string]interface{}{
+			"auto_date_histogram": map[string]interface{}{
+				"field":   "@timestamp",
+				"buckets": options.Buckets,
+			},
+		}
+	}
+	switch options.SplitBy {
+	case "level":
+		histogram["aggs"] = map[string]interface{}{"split": map[string]interface{}{"terms": map[string]interface{}{"field": "level"}}}
+	case "index":
+		histogram["aggs"] = map[string]interface{}{"split": map[string]interface{}{"terms": map[string]interface{}{"field": "_index"}}}
+	}
+
+	query := map[string]interface{}{
+		"query": newFilterQuery(params).source(),
+		"size":  0,
+		"aggs":  map[string]interface{}{"histogram": histogram},
 	}
-	if len(params.Podname) > 0 {
-		podnameSubQuery := map[string]interface{}{
-			"term": map[string]interface{}{
-				"kubernetes.pod_name": params.Podname},
+	result, err := repository.runSearch(ctx, params.Token, query, indices)
+	if err != nil {
+		return logs.Histogram{}, err
+	}
+
+	var response struct {
+		Aggregations struct {
+			Histogram struct {
+				Interval string `json:"interval"`
+				Buckets  []struct {
+					Key      int64 `json:"key"`
+					DocCount int64 `json:"doc_count"`
+					Split    struct {
+						Buckets []struct {
+							Key      string `json:"key"`
+							DocCount int64  `json:"doc_count"`
+						} `json:"buckets"`
+					} `json:"split"`
+				} `json:"buckets"`
+			} `json:"histogram"`
+		} `json:"aggregations"`
+	}
+	data, _ := json.Marshal(result)
+	err = json.Unmarshal(data, &response)
+	if err != nil || result["aggregations"] == nil {
+		repository.log.Error("An error occurred while fetching the histogram", zap.Any("result", result))
+		return logs.Histogram{}, unexpectedResponse()
+	}
+
+	aggregation := response.Aggregations.Histogram
+	logsHistogram := logs.Histogram{Interval: options.Interval, Buckets: []logs.HistogramBucket{}}
+	if len(aggregation.Interval) > 0 {
+		logsHistogram.Interval = aggregation.Interval
+	}
+	for _, bucket := range aggregation.Buckets {
+		histogramBucket := logs.HistogramBucket{
+			Timestamp: time.Unix(0, bucket.Key*int64(time.Millisecond)).UTC(),
+			Count:     bucket.DocCount,
+		}
+		if len(options.SplitBy) > 0 {
+			histogramBucket.Split = map[string]int64{}
+			for _, split := range bucket.Split.Buckets {
+				histogramBucket.Split[split.Key] = split.DocCount
+			}
 		}
-		queryBuilder = append(queryBuilder, podnameSubQuery)
+		logsHistogram.Buckets = append(logsHistogram.Buckets, histogramBucket)
+	}
+	return logsHistogram, nil
+}
+
+// valueFields maps the fields accepted by Values to the indexed fields.
+var valueFields = map[string]string{
+	"namespace": NamespaceName,
+	"pod":       PodName,
+	"container": ContainerName,
+	"label":     FlatLabel,
+	"level":     "level",
+}
+
+// Values lists the most frequent values of a field in the logs matching the
+// filter parameters of FilterLogs with a terms aggregation.
+func (repository *ElasticRepository) Values(ctx context.Context, params logs.Parameters, field string, options logs.ValuesOptions) (logs.FieldValues, error) {
+	err := params.Validate()
+	if err == nil {
+		err = options.Validate(field)
+	}
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.FieldValues{}, err
+	}
+	indices, params := repository.searchIndices(params)
+
+	terms := map[string]interface{}{
+		"field": valueFields[field],
+		"size":  options.Size,
+	}
+	filter := newFilterQuery(params)
+	if len(options.Prefix) > 0 {
+		// labels are multi-valued, so the prefix also has to filter the buckets
+		terms["include"] = regexpQuote(options.Prefix) + ".*"
+		filter.add(appendToQueryBuilder(valueFields[field], Prefix, options.Prefix))
+	}
+	query := map[string]interface{}{
+		"query": filter.source(),
+		"size":  0,
+		"aggs":  map[string]interface{}{"values": map[string]interface{}{"terms": terms}},
 	}
-	if len(params.Index) > 0 {
-		indexSubQuery := map[string]interface{}{
-			"term": map[string]interface{}{
-				"_index": params.Index},
+	result, err := repository.runSearch(ctx, params.Token, query, indices)
+	if err != nil {
+		return logs.FieldValues{}, err
+	}
+
+	var response struct {
+		Aggregations struct {
+			Values struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int64  `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"values"`
+		} `json:"aggregations"`
+	}
+	data, _ := json.Marshal(result)
+	err = json.Unmarshal(data, &response)
+	if err != nil || result["aggregations"] == nil {
+		repository.log.Error("An error occurred while fetching the field values", zap.Any("result", result))
+		return logs.FieldValues{}, unexpectedResponse()
+	}
+
+	fieldValues := logs.FieldValues{Field: field, Values: []logs.FieldValue{}}
+	for _, bucket := range response.Aggregations.Values.Buckets {
+		fieldValues.Values = append(fieldValues.Values, logs.FieldValue{Value: bucket.Key, Count: bucket.DocCount})
+	}
+	return fieldValues, nil
+}
+
+// regexpQuote escapes the characters having a meaning in the Lucene regular
+// expressions used by the include option of terms aggregations.
+func regexpQuote(value string) string {
+	var b strings.Builder
+	for _, c := range value {
+		if strings.ContainsRune(`.?+*|{}[]()"\#@&<>~`, c) {
+			b.WriteRune('\\')
 		}
-		queryBuilder = append(queryBuilder, indexSubQuery)
+		b.WriteRune(c)
 	}
-	if len(params.StartTime) > 0 && len(params.FinishTime) > 0 {
+	return b.String()
+}
 
-		startTime, _ := time.Parse(time.RFC3339Nano, params.StartTime)
-		finishTime, _ := time.Parse(time.RFC3339Nano, params.FinishTime)
+// Context finds the entry whose _id or viaq_msg_id is id, then fetches the
+// entries of its container stream with two search_after queries starting from
+// it: the newest first for the entries before it, which are put back in
+// order, and the oldest first for the entries after it.
+func (repository *ElasticRepository) Context(ctx context.Context, params logs.Parameters, id string, options logs.ContextOptions) (logs.LogContext, error) {
+	err := options.Validate()
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.LogContext{}, err
+	}
+	indices, visible := repository.searchIndices(params.Visibility())
 
-		timeSubquery := map[string]interface{}{
-			"range": map[string]interface{}{
-				"@timestamp": map[string]interface{}{
-					"gte": startTime,
-					"lte": finishTime,
-				},
+	find := newFilterQuery(visible, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []map[string]interface{}{
+				appendToQueryBuilder("values", "ids", []string{id}),
+				appendToQueryBuilder("viaq_msg_id", Term, id),
 			},
-		}
-		queryBuilder = append(queryBuilder, timeSubquery)
+			"minimum_should_match": 1,
+		}})
+	query := map[string]interface{}{
+		"query": find.source(),
+		"size":  1,
+		"sort":  sortQuery("asc"),
 	}
-	if len(params.Level) > 0 {
-		levelSubQuery := map[string]interface{}{
-			"term": map[string]interface{}{
-				"level": params.Level},
+	page, err := repository.getLogsList(ctx, visible.Token, query, 1, indices)
+	if err != nil {
+		return logs.LogContext{}, err
+	}
+	if len(page.Logs) == 0 {
+		return logs.LogContext{}, logs.NotFoundError()
+	}
+	logContext := logs.LogContext{Entry: page.Logs[0]}
+	kubernetes := logContext.Entry.KubernetesMetadata()
+	if len(kubernetes.PodName) == 0 {
+		return logContext, nil // node logs have no stream
+	}
+
+	stream := visible
+	stream.Namespace, stream.Podname, stream.ContainerName = kubernetes.NamespaceName, kubernetes.PodName, kubernetes.ContainerName
+	for _, order := range []string{"desc", "asc"} {
+		query = map[string]interface{}{
+			"query": newFilterQuery(stream).source(),
+			"size":  options.Size,
+			// unlike sortQuery, ties are broken in the same order so that
+			// both queries walk the stream away from the entry
+			"sort": []map[string]interface{}{
+				{"@timestamp": map[string]interface{}{"order": order}},
+				{"_index": map[string]interface{}{"order": order}},
+				{"_id": map[string]interface{}{"order": order}},
+			},
+			"search_after": []interface{}{logContext.Entry.Timestamp.UnixNano() / int64(time.Millisecond), logContext.Entry.Index, logContext.Entry.ID},
+		}
+		page, err = repository.getLogsList(ctx, visible.Token, query, options.Size, indices)
+		if err != nil {
+			return logs.LogContext{}, err
+		}
+		if order == "desc" {
+			for i := len(page.Logs) - 1; i >= 0; i-- {
+				logContext.Before = append(logContext.Before, page.Logs[i])
+			}
+		} else {
+			logContext.After = page.Logs
 		}
-		queryBuilder = append(queryBuilder, levelSubQuery)
 	}
-	maxEntries := 1000 //default value in case params.MaxLogs is nil
-	if len(params.MaxLogs) > 0 {
-		maxLogs, _ := strconv.Atoi(params.MaxLogs)
-		maxEntries = maxLogs
+	return logContext, nil
+}
+
+// exportScroll is how long Elasticsearch keeps the search context of an
+// export alive between two batches.
+const exportScroll = time.Minute
+
+// Export scrolls through the logs matching the filter parameters of FilterLogs
+// and hands them over to handle as every batch arrives.
+func (repository *ElasticRepository) Export(ctx context.Context, params logs.Parameters, handle func([]logs.LogEntry) error) error {
+	err := params.Validate()
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return err
+	}
+	indices, params := repository.searchIndices(params)
+	query := map[string]interface{}{
+		"query": newFilterQuery(params).source(),
+		"size":  logs.ExportBatchSize,
+		"sort":  sortQuery(params.Order),
+	}
+	if source := sourceFilter(params.Projection()); source != nil {
+		query["_source"] = source
+	}
+	jsonQuery, err := json.Marshal(query)
+	if err != nil {
+		repository.log.Error("An error occurred while processing the query", zap.Error(err))
+		return err
 	}
 
-	var sortQuery []map[string]interface{}
-	sortSubQuery := map[string]interface{}{
-		"@timestamp": map[string]interface{}{
-			"order": "desc"},
+	esClient := repository.esClient
+	queryCtx, cancel := repository.queryContext(ctx)
+	defer func() { cancel() }()
+	searchResult, err := esClient.Search(
+		esClient.Search.WithHeader(params.Token),
+		esClient.Search.WithContext(queryCtx),
+		esClient.Search.WithBody(strings.NewReader(string(jsonQuery))),
+		esClient.Search.WithIndex(indices...),
+		esClient.Search.WithScroll(exportScroll),
+	)
+	var scrollID string
+	defer func() {
+		if len(scrollID) > 0 {
+			// the request context may be cancelled already, the scroll has to be released anyway
+			clearResult, err := esClient.ClearScroll(
+				esClient.ClearScroll.WithHeader(params.Token),
+				esClient.ClearScroll.WithContext(context.Background()),
+				esClient.ClearScroll.WithScrollID(scrollID),
+			)
+			if err != nil {
+				repository.log.Error("failed to clear the scroll", zap.Error(err))
+				return
+			}
+			clearResult.Body.Close()
+		}
+	}()
+
+	for {
+		if err != nil {
+			repository.log.Error("failed exec ES query", zap.Error(err))
+			return getError(err)
+		}
+		if searchResult.IsError() {
+			err = responseError(searchResult)
+			searchResult.Body.Close()
+			repository.log.Error("Elasticsearch rejected the query", zap.Error(err))
+			return err
+		}
+		var result map[string]interface{}
+		err = json.NewDecoder(searchResult.Body).Decode(&result)
+		searchResult.Body.Close()
+		cancel()
+		if err != nil {
+			repository.log.Error("Error occurred while decoding JSON", zap.Error(err))
+			return err
+		}
+		if id, ok := result["_scroll_id"].(string); ok {
+			scrollID = id
+		}
+		if _, ok := result["hits"]; !ok {
+			repository.log.Error("An error occurred while fetching logs", zap.Any("result", result))
+			return unexpectedResponse()
+		}
+
+		var page logs.Page
+		page, err = getRelevantLogs(result, logs.ExportBatchSize)
+		if err != nil {
+			repository.log.Error("Error occurred while decoding the search hits", zap.Error(err))
+			return err
+		}
+		if len(page.Logs) == 0 {
+			return nil
+		}
+		err = handle(page.Logs)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		queryCtx, cancel = repository.queryContext(ctx)
+		searchResult, err = esClient.Scroll(
+			esClient.Scroll.WithHeader(params.Token),
+			esClient.Scroll.WithContext(queryCtx),
+			esClient.Scroll.WithScrollID(scrollID),
+			esClient.Scroll.WithScroll(exportScroll),
+		)
 	}
-	sortQuery = append(sortQuery, sortSubQuery)
+}
 
-	query := map[string]interface{}{
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must": queryBuilder,
-			}},
-		"size": maxEntries,
-		"sort": sortQuery,
+func (repository *ElasticRepository) getLogsList(ctx context.Context, token map[string]string, query map[string]interface{}, maxEntries int, indices []string) (logs.Page, error) {
+	log := repository.log
+	result, err := repository.runSearch(ctx, token, query, indices)
+	if err != nil {
+		return logs.Page{}, err
+	}
+	if _, ok := result["hits"]; !ok {
+		log.Error("An error occurred while fetching logs", zap.Any("result", result))
+		return logs.Page{}, unexpectedResponse()
 	}
 
-	logsList, err := getLogsList(params.Token, query, repository.esClient, repository.log)
+	page, err := getRelevantLogs(result, maxEntries)
 	if err != nil {
-		return nil, err
+		log.Error("Error occurred while decoding the search hits", zap.Error(err))
 	}
-	return logsList, nil
+	return page, err
 }
 
-func getLogsList(token map[string]string, query map[string]interface{}, esClient *elasticsearch.Client, log *zap.Logger) ([]string, error) {
+// runSearch sends the query to the log indices and returns the decoded response.
+// The search is abandoned when ctx is done or the query timeout elapses.
+func (repository *ElasticRepository) runSearch(ctx context.Context, token map[string]string, query map[string]interface{}, indices []string) (map[string]interface{}, error) {
+	esClient, log := repository.esClient, repository.log
 
 	jsonQuery, err := json.Marshal(query)
 
@@ -311,24 +700,30 @@ func getLogsList(token map[string]string, query map[string]interface{}, esClient
 		return nil, err
 	}
 
-	var logsList []string // create a slice of type string to append logs to
-
 	var b strings.Builder
 
 	b.WriteString(string(jsonQuery))
 	body := strings.NewReader(b.String())
+	ctx, cancel := repository.queryContext(ctx)
+	defer cancel()
 	searchResult, err := esClient.Search(
 		esClient.Search.WithHeader(token),
-		esClient.Search.WithContext(context.Background()),
+		esClient.Search.WithContext(ctx),
 		esClient.Search.WithBody(body),
-		esClient.Search.WithIndex(constants.InfraIndexName, constants.AppIndexName, constants.AuditIndexName),
+		esClient.Search.WithIndex(indices...),
 		esClient.Search.WithTrackTotalHits(true),
 		esClient.Search.WithPretty(),
 	)
 
 	if err != nil {
 		log.Error("failed exec ES query", zap.Error(err))
-		return logsList, getError(err)
+		return nil, getError(err)
+	}
+	defer searchResult.Body.Close()
+	if searchResult.IsError() {
+		err = responseError(searchResult)
+		log.Error("Elasticsearch rejected the query", zap.Error(err))
+		return nil, err
 	}
 
 	var result map[string]interface{}
@@ -336,34 +731,120 @@ func getLogsList(token map[string]string, query map[string]interface{}, esClient
 	err = json.NewDecoder(searchResult.Body).Decode(&result) //convert searchresult to map[string]interface{}
 	if err != nil {
 		log.Error("Error occurred while decoding JSON", zap.Error(err))
-		return logsList, err
-	}
-	if _, ok := result["hits"]; !ok {
-		log.Error("An error occurred while fetching logs", zap.Any("result", result))
-		return logsList, logs.NotFoundError()
+		return nil, err
 	}
-
-	logsList = getRelevantLogs(result)
-
-	return logsList, nil
+	return result, nil
 }
 
-func getRelevantLogs(result map[string]interface{}) []string {
+func getRelevantLogs(result map[string]interface{}, maxEntries int) (logs.Page, error) {
 	// iterate through the logs and add them to a slice
-	var logsList []string
-	for _, hit := range result["hits"].(map[string]interface{})["hits"].([]interface{}) {
-		log, _ := json.Marshal(hit) //to return logs in JSON
+	var page logs.Page
+	hits := result["hits"].(map[string]interface{})["hits"].([]interface{})
+	for i, hit := range hits {
+		if i == maxEntries {
+			// the extra hit exists, so the page ends at the previous one
+			page.NextCursor = page.EndCursor
+			break
+		}
+		entry, err := newLogEntry(hit)
+		if err != nil {
+			return logs.Page{}, err
+		}
+		page.EndCursor = hitCursor(hit)
+		if entry.Timestamp.IsZero() {
+			// the timestamp was left out of the source or is not RFC 3339, the sort value holds it
+			if cursor, err := logs.ParseCursor(page.EndCursor); err == nil {
+				entry.Timestamp = cursor.Timestamp
+			}
+		}
+		page.Logs = append(page.Logs, entry)
+	}
+	return page, nil
+}
 
-		logsList = append(logsList, string(log))
+// newLogEntry converts a search hit into a LogEntry, keeping the JSON encoded hit for the v1 API.
+func newLogEntry(hit interface{}) (logs.LogEntry, error) {
+	data, _ := json.Marshal(hit) //to return logs in JSON
+	var searchHit struct {
+		Index  string          `json:"_index"`
+		ID     string          `json:"_id"`
+		Source json.RawMessage `json:"_source"`
 	}
-	if len(logsList) == 0 {
-		logsList = append(logsList, "No logs are present or the entry does not exist")
+	err := json.Unmarshal(data, &searchHit)
+	if err != nil {
+		return logs.LogEntry{}, err
 	}
-	return logsList
+	entry, err := logs.NewLogEntry(searchHit.ID, searchHit.Index, searchHit.Source)
+	if err != nil {
+		return logs.LogEntry{}, err
+	}
+	entry.Hit = string(data)
+	return entry, nil
+}
+
+// hitCursor builds the cursor pointing right after the given hit from its sort values.
+func hitCursor(hit interface{}) string {
+	sortValues, ok := hit.(map[string]interface{})["sort"].([]interface{})
+	if !ok || len(sortValues) != 3 {
+		return ""
+	}
+	timestamp, _ := sortValues[0].(float64)
+	index, _ := sortValues[1].(string)
+	id, _ := sortValues[2].(string)
+	return logs.Cursor{
+		Timestamp: time.Unix(0, int64(timestamp)*int64(time.Millisecond)).UTC(),
+		Index:     index,
+		ID:        id,
+	}.Encode()
 }
 
+// getError returns the error reported to the client when Elasticsearch could
+// not be queried.
 func getError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return logs.NewError(logs.KindTimeout, "an error occurred while fetching logs: the query timed out", err)
+	}
+	return logs.NewError(logs.KindUnavailable, "an error occurred while fetching logs: Elasticsearch cannot be reached", err)
+}
+
+// responseError returns the error reported to the client when Elasticsearch
+// answers with an error status, which is kept: a token Elasticsearch does not
+// accept is reported as unauthorized, an index the caller may not read as
+// forbidden and so on.
+func responseError(response *esapi.Response) error {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	_ = json.NewDecoder(response.Body).Decode(&body)
+	var reason struct {
+		Reason string `json:"reason"`
+	}
+	if json.Unmarshal(body.Error, &reason) != nil {
+		_ = json.Unmarshal(body.Error, &reason.Reason) // some errors are plain strings
+	}
+	message := "Elasticsearch answered " + response.Status()
+	if len(reason.Reason) > 0 {
+		message += ": " + reason.Reason
+	}
+
+	kind := logs.KindUnavailable
+	switch response.StatusCode {
+	case http.StatusBadRequest:
+		kind = logs.KindValidation
+	case http.StatusUnauthorized:
+		kind = logs.KindUnauthorized
+	case http.StatusForbidden:
+		kind = logs.KindForbidden
+	case http.StatusNotFound:
+		kind = logs.KindNotFound
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		kind = logs.KindTimeout
+	}
+	return logs.NewError(kind, message, nil)
+}
 
-	err = errors.New("an error occurred while fetching logs")
-	return err
+// unexpectedResponse is returned when a response of Elasticsearch misses the
+// expected hits or aggregations.
+func unexpectedResponse() error {
+	return logs.NewError(logs.KindUnavailable, "an error occurred while fetching logs: unexpected response from Elasticsearch", nil)
 }
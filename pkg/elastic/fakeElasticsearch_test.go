@@ -0,0 +1,745 @@
+package elastic
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/ViaQ/log-exploration-api/pkg/configuration"
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"go.uber.org/zap"
+)
+
+// fakeElasticsearch implements enough of the Elasticsearch API for the queries
+// of ElasticRepository: _search with the bool, ids, term, terms, range, match,
+// match_phrase, prefix, regexp and wildcard queries, search_after, the date_histogram,
+// auto_date_histogram and terms aggregations, scroll and _cluster/health.
+// It records every request so that tests can check the query DSL.
+type fakeElasticsearch struct {
+	server    *httptest.Server
+	documents []fakeDocument
+	scrolls   map[string][]fakeDocument
+	requests  []fakeRequest
+	health    string
+	status    int           // when set, every search fails with this status
+	delay     time.Duration // when set, every search waits this long or until the client goes away
+}
+
+type fakeRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+type fakeDocument struct {
+	Index     string
+	ID        string
+	Timestamp time.Time // to the millisecond, as Elasticsearch stores dates
+	Source    map[string]interface{}
+	RawSource json.RawMessage
+}
+
+// newFakeElasticsearch starts a fake seeded with the hits of test-logs-mapping.json.
+func newFakeElasticsearch(t *testing.T) *fakeElasticsearch {
+	fake := &fakeElasticsearch{scrolls: map[string][]fakeDocument{}, health: "green"}
+	f, err := os.Open("../../test-logs-mapping.json")
+	if err != nil {
+		t.Fatalf("failed to open the test logs. E: %v", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var hit struct {
+			Index  string          `json:"_index"`
+			ID     string          `json:"_id"`
+			Source json.RawMessage `json:"_source"`
+		}
+		err = json.Unmarshal(scanner.Bytes(), &hit)
+		if err != nil {
+			t.Fatalf("failed to decode a test log. E: %v", err)
+		}
+		document := fakeDocument{Index: hit.Index, ID: hit.ID, RawSource: hit.Source}
+		_ = json.Unmarshal(hit.Source, &document.Source)
+		timestamp, _ := time.Parse(time.RFC3339Nano, document.Source["@timestamp"].(string))
+		document.Timestamp = timestamp.Truncate(time.Millisecond)
+		fake.documents = append(fake.documents, document)
+	}
+
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.serveHTTP))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+// repository returns an ElasticRepository sending its requests to the fake.
+func (fake *fakeElasticsearch) repository(t *testing.T) *ElasticRepository {
+	repository, err := NewElasticRepository(zap.L(), &configuration.ElasticsearchConfig{EsAddress: fake.server.URL})
+	if err != nil {
+		t.Fatalf("failed to create the repository. E: %v", err)
+	}
+	return repository.(*ElasticRepository)
+}
+
+// lastBody returns the body of the last request.
+func (fake *fakeElasticsearch) lastBody() string {
+	if len(fake.requests) == 0 {
+		return ""
+	}
+	return fake.requests[len(fake.requests)-1].Body
+}
+
+func (fake *fakeElasticsearch) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	fake.requests = append(fake.requests, fakeRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header, Body: string(body)})
+
+	if fake.delay > 0 && r.URL.Path != "/_cluster/health" {
+		select {
+		case <-time.After(fake.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	var response interface{}
+	var err error
+	switch {
+	case r.URL.Path == "/_cluster/health":
+		response = map[string]interface{}{"cluster_name": "elasticsearch", "status": fake.health}
+	case fake.status != 0:
+		fake.writeError(w, fake.status, "search_phase_execution_exception", "all shards failed")
+		return
+	case r.URL.Path == "/_search/scroll" && r.Method == http.MethodGet:
+		response, err = fake.scroll(r.URL.Query().Get("scroll_id"))
+	case strings.HasPrefix(r.URL.Path, "/_search/scroll/") && r.Method == http.MethodDelete:
+		delete(fake.scrolls, strings.TrimPrefix(r.URL.Path, "/_search/scroll/"))
+		response = map[string]interface{}{"succeeded": true, "num_freed": 1}
+	case strings.HasSuffix(r.URL.Path, "/_search"):
+		indices := strings.Split(strings.Trim(strings.TrimSuffix(r.URL.Path, "/_search"), "/"), ",")
+		response, err = fake.search(indices, body, len(r.URL.Query().Get("scroll")) > 0)
+	default:
+		fake.writeError(w, http.StatusNotFound, "resource_not_found_exception", r.URL.Path+" is not implemented")
+		return
+	}
+	if err != nil {
+		fake.writeError(w, http.StatusBadRequest, "parsing_exception", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (fake *fakeElasticsearch) writeError(w http.ResponseWriter, status int, kind string, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  map[string]interface{}{"type": kind, "reason": reason},
+		"status": status,
+	})
+}
+
+type fakeSearch struct {
+	Query       map[string]interface{}            `json:"query"`
+	Size        *int                              `json:"size"`
+	Sort        []map[string]map[string]string    `json:"sort"`
+	SearchAfter []interface{}                     `json:"search_after"`
+	Aggs        map[string]map[string]interface{} `json:"aggs"`
+	Source      *struct {
+		Includes []string `json:"includes"`
+		Excludes []string `json:"excludes"`
+	} `json:"_source"`
+}
+
+func (fake *fakeElasticsearch) search(indices []string, body []byte, scroll bool) (interface{}, error) {
+	var search fakeSearch
+	if len(body) > 0 {
+		err := json.Unmarshal(body, &search)
+		if err != nil {
+			return nil, err
+		}
+	}
+	size := 10
+	if search.Size != nil {
+		size = *search.Size
+	}
+
+	var documents []fakeDocument
+	for _, document := range fake.documents {
+		if !inIndices(document.Index, indices) {
+			continue
+		}
+		if search.Query != nil {
+			matched, err := matchQuery(search.Query, document)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		documents = append(documents, document)
+	}
+	total := len(documents)
+	err := sortDocuments(search.Sort, documents)
+	if err != nil {
+		return nil, err
+	}
+	if search.SearchAfter != nil {
+		documents = afterDocuments(search.SearchAfter, search.Sort, documents)
+	}
+	if search.Source != nil {
+		projection := logs.Projection{Includes: search.Source.Includes, Excludes: search.Source.Excludes}
+		for i := range documents {
+			documents[i].RawSource, err = projection.Source(documents[i].RawSource)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	response := map[string]interface{}{
+		"took":      1,
+		"timed_out": false,
+	}
+	if scroll {
+		id := fmt.Sprintf("scroll-%d", len(fake.requests))
+		if len(documents) > size {
+			fake.scrolls[id] = documents[size:]
+		} else {
+			fake.scrolls[id] = []fakeDocument{}
+		}
+		response["_scroll_id"] = id
+	}
+	if len(documents) > size {
+		documents = documents[:size]
+	}
+	response["hits"] = map[string]interface{}{
+		"total": map[string]interface{}{"value": total, "relation": "eq"},
+		"hits":  hits(documents, search.Sort != nil),
+	}
+	if search.Aggs != nil {
+		var all []fakeDocument
+		for _, document := range fake.documents {
+			if matched, _ := matchQuery(search.Query, document); matched && inIndices(document.Index, indices) {
+				all = append(all, document)
+			}
+		}
+		aggregations := map[string]interface{}{}
+		for name, aggregation := range search.Aggs {
+			aggregations[name], err = aggregate(aggregation, all)
+			if err != nil {
+				return nil, err
+			}
+		}
+		response["aggregations"] = aggregations
+	}
+	return response, nil
+}
+
+func (fake *fakeElasticsearch) scroll(id string) (interface{}, error) {
+	documents, ok := fake.scrolls[id]
+	if !ok {
+		return nil, fmt.Errorf("no search context found for id [%s]", id)
+	}
+	batch := documents
+	if len(batch) > logs.ExportBatchSize {
+		batch = batch[:logs.ExportBatchSize]
+	}
+	fake.scrolls[id] = documents[len(batch):]
+	return map[string]interface{}{
+		"_scroll_id": id,
+		"hits":       map[string]interface{}{"hits": hits(batch, true)},
+	}, nil
+}
+
+func hits(documents []fakeDocument, sorted bool) []interface{} {
+	hits := []interface{}{}
+	for _, document := range documents {
+		hit := map[string]interface{}{
+			"_index":  document.Index,
+			"_type":   "_doc",
+			"_id":     document.ID,
+			"_score":  nil,
+			"_source": document.RawSource,
+		}
+		if sorted {
+			hit["sort"] = []interface{}{document.Timestamp.UnixNano() / int64(time.Millisecond), document.Index, document.ID}
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+// inIndices reports whether the index is one of the indices or belongs to
+// one of their aliases, app, infra or audit for app-000001 and so on.
+func inIndices(index string, indices []string) bool {
+	for _, name := range indices {
+		if index == name || strings.HasPrefix(index, name+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+func sortDocuments(sortFields []map[string]map[string]string, documents []fakeDocument) error {
+	for _, sortField := range sortFields {
+		for field := range sortField {
+			if field != "@timestamp" && field != "_index" && field != "_id" {
+				return fmt.Errorf("sorting on %s is not implemented", field)
+			}
+		}
+	}
+	sort.SliceStable(documents, func(i, j int) bool {
+		return compareDocuments(sortFields, documents[i], documents[j]) < 0
+	})
+	return nil
+}
+
+// compareDocuments compares two documents on the sort fields.
+func compareDocuments(sortFields []map[string]map[string]string, a fakeDocument, b fakeDocument) int {
+	for _, sortField := range sortFields {
+		for field, options := range sortField {
+			result := 0
+			switch {
+			case field == "@timestamp" && a.Timestamp.Before(b.Timestamp), field == "_index" && a.Index < b.Index, field == "_id" && a.ID < b.ID:
+				result = -1
+			case field == "@timestamp" && a.Timestamp.After(b.Timestamp), field == "_index" && a.Index > b.Index, field == "_id" && a.ID > b.ID:
+				result = 1
+			}
+			if options["order"] == "desc" {
+				result = -result
+			}
+			if result != 0 {
+				return result
+			}
+		}
+	}
+	return 0
+}
+
+// afterDocuments drops the sorted documents up to the search_after values.
+func afterDocuments(values []interface{}, sortFields []map[string]map[string]string, documents []fakeDocument) []fakeDocument {
+	milliseconds, _ := values[0].(float64)
+	index, _ := values[1].(string)
+	id, _ := values[2].(string)
+	cursor := fakeDocument{Timestamp: time.Unix(0, int64(milliseconds)*int64(time.Millisecond)), Index: index, ID: id}
+	for i, document := range documents {
+		if compareDocuments(sortFields, document, cursor) > 0 {
+			return documents[i:]
+		}
+	}
+	return nil
+}
+
+// matchQuery evaluates a query on a document.
+func matchQuery(query map[string]interface{}, document fakeDocument) (bool, error) {
+	for kind, value := range query {
+		clause, ok := value.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("[%s] malformed query", kind)
+		}
+		var matched bool
+		var err error
+		switch kind {
+		case "bool":
+			matched, err = matchBool(clause, document)
+		case "match_all":
+			matched = true
+		case "ids":
+			values, _ := clause["values"].([]interface{})
+			for _, id := range values {
+				matched = matched || id == document.ID
+			}
+		case "exists":
+			field, _ := clause["field"].(string)
+			for _, value := range fieldValues(field, document) {
+				matched = matched || len(value) > 0
+			}
+		case "term", "terms", "range", "match", "match_phrase", "prefix", "regexp", "wildcard":
+			matched, err = matchField(kind, clause, document)
+		default:
+			return false, fmt.Errorf("unknown query [%s]", kind)
+		}
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchBool(clause map[string]interface{}, document fakeDocument) (bool, error) {
+	minimumShouldMatch, _ := clause["minimum_should_match"].(float64)
+	shouldMatch := 0
+	for occur, value := range clause {
+		if occur == "minimum_should_match" {
+			continue
+		}
+		var queries []interface{}
+		switch value := value.(type) {
+		case []interface{}:
+			queries = value
+		case map[string]interface{}:
+			queries = []interface{}{value}
+		case nil:
+		default:
+			return false, fmt.Errorf("[bool] malformed %s", occur)
+		}
+		for _, query := range queries {
+			subQuery, _ := query.(map[string]interface{})
+			matched, err := matchQuery(subQuery, document)
+			if err != nil {
+				return false, err
+			}
+			switch occur {
+			case "must", "filter":
+				if !matched {
+					return false, nil
+				}
+			case "must_not":
+				if matched {
+					return false, nil
+				}
+			case "should":
+				if matched {
+					shouldMatch++
+				}
+			default:
+				return false, fmt.Errorf("[bool] %s is not implemented", occur)
+			}
+		}
+	}
+	return shouldMatch >= int(minimumShouldMatch), nil
+}
+
+func matchField(kind string, clause map[string]interface{}, document fakeDocument) (bool, error) {
+	if len(clause) != 1 {
+		return false, fmt.Errorf("[%s] query doesn't support multiple fields", kind)
+	}
+	for field, value := range clause {
+		values := fieldValues(field, document)
+		switch kind {
+		case "term", "prefix", "regexp", "wildcard":
+			if options, ok := value.(map[string]interface{}); ok {
+				value = options["value"]
+			}
+			expected, ok := value.(string)
+			if !ok {
+				return false, fmt.Errorf("[%s] query on %s requires a string", kind, field)
+			}
+			for _, v := range values {
+				switch kind {
+				case "term":
+					if v == expected {
+						return true, nil
+					}
+				case "prefix":
+					if strings.HasPrefix(v, expected) {
+						return true, nil
+					}
+				case "wildcard":
+					pattern := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(expected))
+					if regexp.MustCompile("^" + pattern + "$").MatchString(v) {
+						return true, nil
+					}
+				case "regexp":
+					matched, err := regexp.MatchString("^(?:"+expected+")$", v)
+					if err != nil {
+						return false, err
+					}
+					if matched {
+						return true, nil
+					}
+				}
+			}
+		case "terms":
+			expected, ok := value.([]interface{})
+			if !ok {
+				return false, fmt.Errorf("[terms] query on %s requires an array", field)
+			}
+			for _, e := range expected {
+				for _, v := range values {
+					if v == e {
+						return true, nil
+					}
+				}
+			}
+		case "range":
+			return matchRange(field, value, document)
+		case "match", "match_phrase":
+			text := value
+			operator := "or"
+			if options, ok := value.(map[string]interface{}); ok {
+				text = options["query"]
+				if o, ok := options["operator"].(string); ok {
+					operator = strings.ToLower(o)
+				}
+			}
+			query, ok := text.(string)
+			if !ok {
+				return false, fmt.Errorf("[%s] query on %s requires a string", kind, field)
+			}
+			for _, v := range values {
+				if field != "message" {
+					// keyword fields are not analyzed
+					if v == query {
+						return true, nil
+					}
+					continue
+				}
+				if kind == "match_phrase" && containsPhrase(analyze(v), analyze(query)) {
+					return true, nil
+				}
+				if kind == "match" && containsTerms(analyze(v), analyze(query), operator == "and") {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+func matchRange(field string, value interface{}, document fakeDocument) (bool, error) {
+	bounds, ok := value.(map[string]interface{})
+	if field != "@timestamp" || !ok {
+		return false, fmt.Errorf("[range] query on %s is not implemented", field)
+	}
+	for operator, bound := range bounds {
+		text, _ := bound.(string)
+		limit, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse date field [%v]", bound)
+		}
+		limit = limit.Truncate(time.Millisecond)
+		switch operator {
+		case "gte":
+			ok = !document.Timestamp.Before(limit)
+		case "lte":
+			ok = !document.Timestamp.After(limit)
+		case "gt":
+			ok = document.Timestamp.After(limit)
+		case "lt":
+			ok = document.Timestamp.Before(limit)
+		default:
+			return false, fmt.Errorf("[range] query does not support [%s]", operator)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// fieldValues returns the values of a field of the document. The .raw
+// sub-field of a text field holds the same values.
+func fieldValues(field string, document fakeDocument) []string {
+	switch field {
+	case "_index":
+		values := []string{document.Index}
+		if i := strings.LastIndex(document.Index, "-"); i > 0 {
+			values = append(values, document.Index[:i])
+		}
+		return values
+	case "_id":
+		return []string{document.ID}
+	}
+	var value interface{} = document.Source
+	for _, key := range strings.Split(strings.TrimSuffix(field, ".raw"), ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// analyze splits a text into lower case words, like the standard analyzer.
+func analyze(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func containsTerms(tokens []string, terms []string, all bool) bool {
+	found := 0
+	for _, term := range terms {
+		for _, token := range tokens {
+			if token == term {
+				found++
+				break
+			}
+		}
+	}
+	if all {
+		return found == len(terms)
+	}
+	return found > 0
+}
+
+func containsPhrase(tokens []string, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		if reflect.DeepEqual(tokens[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return len(phrase) == 0
+}
+
+// aggregate computes a date_histogram, auto_date_histogram or terms aggregation.
+func aggregate(aggregation map[string]interface{}, documents []fakeDocument) (interface{}, error) {
+	subAggregations, _ := aggregation["aggs"].(map[string]interface{})
+	for kind, value := range aggregation {
+		options, _ := value.(map[string]interface{})
+		switch kind {
+		case "aggs":
+		case "terms":
+			return termsAggregation(options, documents)
+		case "date_histogram", "auto_date_histogram":
+			return dateHistogram(kind, options, subAggregations, documents)
+		default:
+			return nil, fmt.Errorf("unknown aggregation [%s]", kind)
+		}
+	}
+	return nil, fmt.Errorf("aggregation without type")
+}
+
+func termsAggregation(options map[string]interface{}, documents []fakeDocument) (interface{}, error) {
+	field, _ := options["field"].(string)
+	size := 10
+	if s, ok := options["size"].(float64); ok {
+		size = int(s)
+	}
+	var include *regexp.Regexp
+	if pattern, ok := options["include"].(string); ok {
+		var err error
+		include, err = regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, err
+		}
+	}
+	counts := map[string]int{}
+	for _, document := range documents {
+		values := fieldValues(field, document)
+		if field == "_index" {
+			values = values[:1]
+		}
+		for _, value := range values {
+			if include == nil || include.MatchString(value) {
+				counts[value]++
+			}
+		}
+	}
+	buckets := []map[string]interface{}{}
+	for key, count := range counts {
+		buckets = append(buckets, map[string]interface{}{"key": key, "doc_count": count})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i]["doc_count"] != buckets[j]["doc_count"] {
+			return buckets[i]["doc_count"].(int) > buckets[j]["doc_count"].(int)
+		}
+		return buckets[i]["key"].(string) < buckets[j]["key"].(string)
+	})
+	if len(buckets) > size {
+		buckets = buckets[:size]
+	}
+	return map[string]interface{}{"buckets": buckets}, nil
+}
+
+func dateHistogram(kind string, options map[string]interface{}, subAggregations map[string]interface{}, documents []fakeDocument) (interface{}, error) {
+	if options["field"] != "@timestamp" {
+		return nil, fmt.Errorf("[%s] on %v is not implemented", kind, options["field"])
+	}
+	var interval string
+	var from, to time.Time
+	if kind == "date_histogram" {
+		interval, _ = options["fixed_interval"].(string)
+		if bounds, ok := options["extended_bounds"].(map[string]interface{}); ok {
+			min, _ := bounds["min"].(string)
+			max, _ := bounds["max"].(string)
+			from, _ = time.Parse(time.RFC3339Nano, min)
+			to, _ = time.Parse(time.RFC3339Nano, max)
+		}
+	} else if len(documents) > 0 {
+		buckets, _ := options["buckets"].(float64)
+		first, last := documents[0].Timestamp, documents[0].Timestamp
+		for _, document := range documents {
+			if document.Timestamp.Before(first) {
+				first = document.Timestamp
+			}
+			if document.Timestamp.After(last) {
+				last = document.Timestamp
+			}
+		}
+		interval = logs.AutoInterval(first, last, int(buckets))
+	} else {
+		return map[string]interface{}{"buckets": []interface{}{}, "interval": "1s"}, nil
+	}
+	width, err := logs.ParseInterval(interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse setting [fixed_interval] with value [%s]", interval)
+	}
+
+	grouped := map[int64][]fakeDocument{}
+	var keys []int64
+	for _, document := range documents {
+		key := document.Timestamp.UnixNano() - document.Timestamp.UnixNano()%int64(width)
+		grouped[key] = append(grouped[key], document)
+	}
+	for key := range grouped {
+		keys = append(keys, key)
+	}
+	for _, bound := range []time.Time{from, to} {
+		if !bound.IsZero() {
+			keys = append(keys, bound.UnixNano()-bound.UnixNano()%int64(width))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	buckets := []interface{}{}
+	for key := int64(0); len(keys) > 0 && (key == 0 || key <= keys[len(keys)-1]); key += int64(width) {
+		if key == 0 {
+			key = keys[0]
+		}
+		bucket := map[string]interface{}{
+			"key_as_string": time.Unix(0, key).UTC().Format(time.RFC3339),
+			"key":           key / int64(time.Millisecond),
+			"doc_count":     len(grouped[key]),
+		}
+		for name, value := range subAggregations {
+			subAggregation, _ := value.(map[string]interface{})
+			bucket[name], err = aggregate(subAggregation, grouped[key])
+			if err != nil {
+				return nil, err
+			}
+		}
+		buckets = append(buckets, bucket)
+	}
+	histogram := map[string]interface{}{"buckets": buckets}
+	if kind == "auto_date_histogram" {
+		histogram["interval"] = interval
+	}
+	return histogram, nil
+}
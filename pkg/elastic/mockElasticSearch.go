@@ -1,21 +1,24 @@
 package elastic
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
 	"github.com/ViaQ/log-exploration-api/pkg/logs"
 )
 
+// MockedElasticsearchProvider keeps structured log entries in memory and
+// answers every method with the filters mirroring the queries of ElasticRepository.
 type MockedElasticsearchProvider struct {
-	App            map[time.Time][]string
-	Infra          map[time.Time][]string
-	Audit          map[time.Time][]string
+	entries        []logs.LogEntry
 	checkReadiness bool
 }
 
-func (m *MockedElasticsearchProvider) CheckReadiness() bool {
+func (m *MockedElasticsearchProvider) CheckReadiness(ctx context.Context) bool {
 	if m.checkReadiness == true {
 		return true
 	} else {
@@ -25,227 +28,177 @@ func (m *MockedElasticsearchProvider) CheckReadiness() bool {
 
 func NewMockedElastisearchProvider() *MockedElasticsearchProvider {
 	return &MockedElasticsearchProvider{
-		App:            map[time.Time][]string{},
-		Infra:          map[time.Time][]string{},
-		Audit:          map[time.Time][]string{},
 		checkReadiness: true,
 	}
 }
 
+// Annotations of the logs given to PutDataAtTime, followed by comma separated values.
 const (
 	Level         = "level: "
 	containerName = "container_name: "
 	podName       = "pod_name: "
 	namespaceName = "namespace_name: "
+	flatLabels    = "flat_labels: "
 )
 
 func (m *MockedElasticsearchProvider) UpdateReadinessState(checkReadiness bool) {
 	m.checkReadiness = checkReadiness
 }
 
+// PutDataAtTime stores logs written at the given time in the app, infra or
+// audit index. Every log is the message and the hit of its entry, and its
+// annotations, such as "namespace_name: openshift-monitoring, level: error,",
+// set the Kubernetes metadata and the level of the entry.
 func (m *MockedElasticsearchProvider) PutDataAtTime(logTime time.Time, index string, data []string) error {
-	switch strings.ToLower(index) {
-	case "app":
-		m.App[logTime] = data
-		return nil
-	case "infra":
-		m.Infra[logTime] = data
-		return nil
-	case "audit":
-		m.Audit[logTime] = data
-		return nil
+	index = strings.ToLower(index)
+	switch index {
+	case "app", "infra", "audit":
 	default:
 		return errors.New("unknown index")
 	}
-}
-
-func (m *MockedElasticsearchProvider) Cleanup() {
-	m.App = map[time.Time][]string{}
-	m.Infra = map[time.Time][]string{}
-	m.Audit = map[time.Time][]string{}
-}
-
-func mockedFilterHelper(params logs.Parameters, m *MockedElasticsearchProvider) ([]string, error) {
-	lg := make(map[time.Time][]string)
-	if len(params.Index) > 0 {
-		switch strings.ToLower(params.Index) {
-		case "app":
-			lg = m.App
-		case "infra":
-			lg = m.Infra
-		case "audit":
-			lg = m.Audit
+	for i, log := range data {
+		entry := logs.LogEntry{
+			ID:        fmt.Sprintf("%s-%d-%06d", index, logTime.UnixNano(), i),
+			Index:     index,
+			Timestamp: logTime,
+			Message:   log,
+			Hit:       log,
 		}
-	} else {
-		for k, v := range m.App {
-			if v != nil {
-				lg[k] = v
-			}
+		if levels := mockedFieldValues(Level, log); len(levels) > 0 {
+			entry.Level = levels[0]
 		}
-		for k, v := range m.Infra {
-			if v != nil {
-				lg[k] = v
-			}
+		kubernetes := &logs.KubernetesMetadata{FlatLabels: mockedFieldValues(flatLabels, log)}
+		if values := mockedFieldValues(namespaceName, log); len(values) > 0 {
+			kubernetes.NamespaceName = values[0]
 		}
-		for k, v := range m.Audit {
-			if v != nil {
-				lg[k] = v
-			}
+		if values := mockedFieldValues(podName, log); len(values) > 0 {
+			kubernetes.PodName = values[0]
 		}
-	}
-	if len(lg) == 0 {
-		return nil, logs.NotFoundError()
-	}
-	var result []string
-	if len(params.FinishTime) > 0 && len(params.StartTime) > 0 {
-		start, _ := time.Parse(time.RFC3339Nano, params.StartTime)
-		finish, _ := time.Parse(time.RFC3339Nano, params.FinishTime)
-		for k, v := range lg {
-			if k.After(start) && k.Before(finish) {
-				result = append(result, v...)
-			}
+		if values := mockedFieldValues(containerName, log); len(values) > 0 {
+			kubernetes.ContainerName = values[0]
 		}
-	} else {
-		for _, v := range lg {
-			result = append(result, v...)
+		if !reflect.DeepEqual(*kubernetes, logs.KubernetesMetadata{}) {
+			entry.Kubernetes = kubernetes
 		}
+		m.entries = append(m.entries, entry)
 	}
-	if len(result) > 0 {
-		return result, nil
-	} else {
-		return nil, logs.NotFoundError()
-	}
+	return nil
+}
+
+// PutLogs stores log entries as they are, such as the hits of test-logs-mapping.json.
+func (m *MockedElasticsearchProvider) PutLogs(entries ...logs.LogEntry) {
+	m.entries = append(m.entries, entries...)
+}
+
+func (m *MockedElasticsearchProvider) Cleanup() {
+	m.entries = nil
 }
 
-func generateIntermediateLogs(typeOfLog string, parameter string, logs []string) []string {
-	var resultantLogs []string
-	for _, v := range logs {
-		str := typeOfLog + parameter
-		if strings.Contains(v, str) {
-			resultantLogs = append(resultantLogs, v)
+// mockedFieldValues returns the comma separated values following the annotation in the log.
+func mockedFieldValues(field string, log string) []string {
+	i := strings.Index(log, field)
+	if i < 0 {
+		return nil
+	}
+	var values []string
+	for _, value := range strings.Split(log[i+len(field):], ",") {
+		value = strings.TrimSpace(value)
+		if strings.Contains(value, ": ") {
+			break // the next field starts
+		}
+		if len(value) > 0 {
+			values = append(values, value)
 		}
 	}
-	return resultantLogs
+	return values
 }
 
-func (m *MockedElasticsearchProvider) Logs(params logs.Parameters) ([]string, error) {
-	resultantLogs, err := mockedFilterHelper(params, m)
+// search returns the page of the stored entries picked by the filter.
+func (m *MockedElasticsearchProvider) search(params logs.Parameters, filter logs.Filter) (logs.Page, error) {
+	err := params.Validate()
 	if err != nil {
-		return nil, logs.NotFoundError()
-	}
-	if len(params.Level) > 0 {
-		resultantLogs = generateIntermediateLogs(Level, params.Level, resultantLogs)
-	}
-	if len(resultantLogs) == 0 {
-		return nil, logs.NotFoundError()
+		return logs.Page{}, err
 	}
-	return resultantLogs, nil
+	return logs.Paginate(params, logs.Select(m.entries, filter)), nil
 }
 
-func (m *MockedElasticsearchProvider) FilterLogs(params logs.Parameters) ([]string, error) {
+func (m *MockedElasticsearchProvider) Logs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return m.search(params, logs.LogsFilter(params))
+}
 
-	tempLogsStore, err := mockedFilterHelper(params, m)
-	var resultantLogs []string
-	if err != nil {
-		return nil, logs.NotFoundError()
-	}
-	if len(params.Podname) > 0 {
-		for _, v := range tempLogsStore {
-			pod := "pod_name: " + params.Podname
-			if strings.Contains(v, pod) {
-				resultantLogs = append(resultantLogs, v)
-			}
-		}
-		tempLogsStore = resultantLogs
-		resultantLogs = []string{}
-	}
-	if len(params.Namespace) > 0 {
-		for _, v := range tempLogsStore {
-			ns := "namespace_name: " + params.Namespace
-			if strings.Contains(v, ns) {
-				resultantLogs = append(resultantLogs, v)
-			}
-		}
-	} else {
-		resultantLogs = tempLogsStore
-	}
+func (m *MockedElasticsearchProvider) FilterLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return m.search(params, params.Filter())
+}
 
-	if len(resultantLogs) == 0 {
-		return nil, logs.NotFoundError()
-	}
-	return resultantLogs, nil
+func (m *MockedElasticsearchProvider) FilterNamespaceLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return m.search(params, logs.NamespaceFilter(params))
+}
+
+func (m *MockedElasticsearchProvider) FilterPodLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return m.search(params, logs.PodFilter(params))
+}
+
+func (m *MockedElasticsearchProvider) FilterContainerLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return m.search(params, logs.ContainerFilter(params))
 }
-func (m *MockedElasticsearchProvider) FilterContainerLogs(params logs.Parameters) ([]string, error) {
-	resultantLogs, err := mockedFilterHelper(params, m)
+
+func (m *MockedElasticsearchProvider) FilterLabelLogs(ctx context.Context, params logs.Parameters, labelList []string) (logs.Page, error) {
+	return m.search(params, logs.LabelFilter(params, labelList))
+}
+
+func (m *MockedElasticsearchProvider) FilterEntityLogs(ctx context.Context, params logs.Parameters, kind string, name string) (logs.Page, error) {
+	selector, err := logs.NewWorkloadSelector(kind, name)
 	if err != nil {
-		return nil, logs.NotFoundError()
+		return logs.Page{}, err
 	}
-	resultantLogs = generateIntermediateLogs(namespaceName, params.Namespace, resultantLogs)
-	resultantLogs = generateIntermediateLogs(podName, params.Podname, resultantLogs)
-	resultantLogs = generateIntermediateLogs(containerName, params.ContainerName, resultantLogs)
-	if len(params.Level) > 0 {
-		resultantLogs = generateIntermediateLogs(Level, params.Level, resultantLogs)
+	return m.search(params, logs.EntityFilter(params, selector))
+}
+
+func (m *MockedElasticsearchProvider) Search(ctx context.Context, params logs.Parameters, filter logs.Condition) (logs.Page, error) {
+	err := params.Validate()
+	if err == nil {
+		err = filter.Validate()
 	}
-	if len(resultantLogs) == 0 {
-		return nil, logs.NotFoundError()
+	if err != nil {
+		return logs.Page{}, err
 	}
-	return resultantLogs, nil
+	return m.search(params, logs.SearchFilter(params, filter))
 }
 
-func (m *MockedElasticsearchProvider) FilterLabelLogs(params logs.Parameters, labelList []string) ([]string, error) {
-	resultantLogs, err := mockedFilterHelper(params, m)
+func (m *MockedElasticsearchProvider) Histogram(ctx context.Context, params logs.Parameters, options logs.HistogramOptions) (logs.Histogram, error) {
+	err := params.Validate()
+	if err == nil {
+		err = options.Validate()
+	}
 	if err != nil {
-		return nil, logs.NotFoundError()
-	}
-	for _, label := range labelList {
-		tempLogsStore := resultantLogs
-		resultantLogs = []string{}
-		for _, v := range tempLogsStore {
-			if label == "" {
-				resultantLogs = append(resultantLogs, v)
-				continue
-			}
-			if strings.Contains(v, label) {
-				resultantLogs = append(resultantLogs, v)
-			}
-		}
+		return logs.Histogram{}, err
 	}
-	if len(params.Level) > 0 {
-		resultantLogs = generateIntermediateLogs(Level, params.Level, resultantLogs)
+	return logs.CountHistogram(logs.Select(m.entries, params.Filter()), params, options)
+}
+
+func (m *MockedElasticsearchProvider) Values(ctx context.Context, params logs.Parameters, field string, options logs.ValuesOptions) (logs.FieldValues, error) {
+	err := params.Validate()
+	if err == nil {
+		err = options.Validate(field)
 	}
-	if len(resultantLogs) == 0 {
-		return nil, logs.NotFoundError()
+	if err != nil {
+		return logs.FieldValues{}, err
 	}
-	return resultantLogs, nil
+	return logs.CountValues(logs.Select(m.entries, params.Filter()), field, options), nil
 }
 
-func (m *MockedElasticsearchProvider) FilterPodLogs(params logs.Parameters) ([]string, error) {
-	resultantLogs, err := mockedFilterHelper(params, m)
+func (m *MockedElasticsearchProvider) Context(ctx context.Context, params logs.Parameters, id string, options logs.ContextOptions) (logs.LogContext, error) {
+	err := options.Validate()
 	if err != nil {
-		return nil, logs.NotFoundError()
-	}
-	resultantLogs = generateIntermediateLogs(namespaceName, params.Namespace, resultantLogs)
-	resultantLogs = generateIntermediateLogs(podName, params.Podname, resultantLogs)
-	if len(params.Level) > 0 {
-		resultantLogs = generateIntermediateLogs(Level, params.Level, resultantLogs)
+		return logs.LogContext{}, err
 	}
-	if len(resultantLogs) == 0 {
-		return nil, logs.NotFoundError()
-	}
-	return resultantLogs, nil
+	return logs.ContextEntries(params, m.entries, id, options)
 }
 
-func (m *MockedElasticsearchProvider) FilterNamespaceLogs(params logs.Parameters) ([]string, error) {
-	resultantLogs, err := mockedFilterHelper(params, m)
+func (m *MockedElasticsearchProvider) Export(ctx context.Context, params logs.Parameters, handle func([]logs.LogEntry) error) error {
+	err := params.Validate()
 	if err != nil {
-		return nil, logs.NotFoundError()
-	}
-	resultantLogs = generateIntermediateLogs(namespaceName, params.Namespace, resultantLogs)
-	if len(params.Level) > 0 {
-		resultantLogs = generateIntermediateLogs(Level, params.Level, resultantLogs)
-	}
-	if len(resultantLogs) == 0 {
-		return nil, logs.NotFoundError()
+		return err
 	}
-	return resultantLogs, nil
+	return logs.ExportEntries(ctx, params, logs.Select(m.entries, params.Filter()), handle)
 }
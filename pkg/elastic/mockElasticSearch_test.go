@@ -0,0 +1,18 @@
+package elastic
+
+import (
+	"testing"
+
+	"github.com/ViaQ/log-exploration-api/pkg/file"
+	"github.com/ViaQ/log-exploration-api/pkg/logs/logstest"
+)
+
+func TestMockConformance(t *testing.T) {
+	entries, err := file.ReadEntries("../../test-logs-mapping.json")
+	if err != nil {
+		t.Fatalf("failed to read the test logs. E: %v", err)
+	}
+	provider := NewMockedElastisearchProvider()
+	provider.PutLogs(entries...)
+	logstest.TestProvider(t, provider)
+}
@@ -0,0 +1,214 @@
+package elastic
+
+import (
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+)
+
+// filterQuery builds the bool query matching the filter parameters shared by
+// every method, which add the clauses of their own parameters to it.
+type filterQuery struct {
+	must    []map[string]interface{}
+	mustNot []map[string]interface{}
+}
+
+// newFilterQuery starts a query with the given clauses followed by those of
+// the filter parameters: the fields, index, time range, visible namespaces
+// and indices and message query. The parameters must have been validated.
+func newFilterQuery(params logs.Parameters, clauses ...map[string]interface{}) *filterQuery {
+	query := &filterQuery{must: clauses, mustNot: []map[string]interface{}{}}
+	query.field(NamespaceName, params.FieldFilter("namespace"))
+	query.field(PodName, params.FieldFilter("pod"))
+	query.field(ContainerName, params.FieldFilter("container"))
+	if len(params.Index) > 0 {
+		query.add(appendToQueryBuilder("_index", Term, params.Index))
+	}
+	timeRange, _ := params.TimeRange(time.Now())
+	if bounds := rangeBounds(timeRange, "gte", "lte"); len(bounds) > 0 {
+		query.add(map[string]interface{}{
+			"range": map[string]interface{}{
+				"@timestamp": bounds,
+			},
+		})
+	}
+	query.field("level", params.FieldFilter("level"))
+	if params.Namespaces != nil {
+		query.add(appendToQueryBuilder(NamespaceName, Terms, params.Namespaces))
+	}
+	if params.Indices != nil {
+		query.add(appendToQueryBuilder("_index", Terms, params.Indices))
+	}
+	query.message(params.Query)
+	return query
+}
+
+// add requires the clause to match.
+func (query *filterQuery) add(clause map[string]interface{}) {
+	query.must = append(query.must, clause)
+}
+
+// field requires the field to have one of the included values of the filter
+// and none of the excluded ones.
+func (query *filterQuery) field(field string, filter logs.FieldFilter) {
+	if len(filter.Include) > 0 {
+		query.add(valuesQuery(field, filter.Include))
+	}
+	if len(filter.Exclude) > 0 {
+		query.mustNot = append(query.mustNot, valuesQuery(field, filter.Exclude))
+	}
+}
+
+// message adds the full-text conditions on the log message, excluding the
+// negated words and phrases.
+func (query *filterQuery) message(messageQuery string) {
+	terms, _ := logs.ParseMessageQuery(messageQuery)
+	for _, term := range terms {
+		var subQuery map[string]interface{}
+		if term.Phrase {
+			subQuery = appendToQueryBuilder(Message, MatchPhrase, term.Text)
+		} else {
+			value := map[string]interface{}{
+				"query": term.Text, "operator": "AND"}
+			subQuery = appendToQueryBuilder(Message, Match, value)
+		}
+		if term.Negate {
+			query.mustNot = append(query.mustNot, subQuery)
+		} else {
+			query.add(subQuery)
+		}
+	}
+}
+
+// source returns the query DSL.
+func (query *filterQuery) source() map[string]interface{} {
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must":     query.must,
+			"must_not": query.mustNot,
+		}}
+}
+
+// rangeBounds returns the bounds of the time range that are set under the
+// given names, such as gte and lte.
+func rangeBounds(timeRange logs.TimeRange, start string, finish string) map[string]interface{} {
+	bounds := map[string]interface{}{}
+	if !timeRange.Start.IsZero() {
+		bounds[start] = timeRange.Start
+	}
+	if !timeRange.Finish.IsZero() {
+		bounds[finish] = timeRange.Finish
+	}
+	return bounds
+}
+
+// sourceFilter returns the _source of a search keeping the fields of the
+// projection, or nil to keep the whole source.
+func sourceFilter(projection logs.Projection) map[string]interface{} {
+	if projection.Empty() {
+		return nil
+	}
+	source := map[string]interface{}{}
+	if len(projection.Includes) > 0 {
+		source["includes"] = projection.Includes
+	}
+	if len(projection.Excludes) > 0 {
+		source["excludes"] = projection.Excludes
+	}
+	return source
+}
+
+// searchFields maps the fields of a search filter to the fields of the documents.
+var searchFields = map[string]string{
+	"namespace":   NamespaceName,
+	"pod":         PodName,
+	"container":   ContainerName,
+	"label":       FlatLabel,
+	"level":       "level",
+	"hostname":    "hostname",
+	"viaq_msg_id": "viaq_msg_id",
+	"index":       "_index",
+	"message":     Message,
+	"timestamp":   "@timestamp",
+}
+
+// conditionQuery compiles a validated search filter into a query, resolving
+// times against now.
+func conditionQuery(condition logs.Condition, now time.Time) map[string]interface{} {
+	field := searchFields[condition.Field]
+	switch condition.Operator() {
+	case "and", "or":
+		children := condition.And
+		occur := "must"
+		if condition.Operator() == "or" {
+			children, occur = condition.Or, "should"
+		}
+		clauses := make([]map[string]interface{}, 0, len(children))
+		for _, child := range children {
+			clauses = append(clauses, conditionQuery(child, now))
+		}
+		clause := map[string]interface{}{occur: clauses}
+		if occur == "should" {
+			clause["minimum_should_match"] = 1
+		}
+		return map[string]interface{}{"bool": clause}
+	case "not":
+		return map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must_not": []map[string]interface{}{conditionQuery(*condition.Not, now)},
+			}}
+	case "eq":
+		return appendToQueryBuilder(field, Term, *condition.Eq)
+	case "in":
+		return appendToQueryBuilder(field, Terms, condition.In)
+	case "prefix":
+		return appendToQueryBuilder(field, Prefix, *condition.Prefix)
+	case "range":
+		times, _ := condition.Range.Times(now)
+		return appendToQueryBuilder(field, "range", times)
+	case "exists":
+		exists := map[string]interface{}{"exists": map[string]interface{}{"field": field}}
+		if *condition.Exists {
+			return exists
+		}
+		return map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must_not": []map[string]interface{}{exists},
+			}}
+	case "match":
+		query := &filterQuery{must: []map[string]interface{}{}, mustNot: []map[string]interface{}{}}
+		query.message(*condition.Match)
+		return query.source()
+	}
+	return map[string]interface{}{"match_none": map[string]interface{}{}}
+}
+
+// valuesQuery matches a field having one of the values: a term query for a
+// single value, a terms query for several and wildcard queries for the values
+// having wildcards.
+func valuesQuery(field string, values []string) map[string]interface{} {
+	var exact []string
+	var clauses []map[string]interface{}
+	for _, value := range values {
+		if logs.IsPattern(value) {
+			clauses = append(clauses, appendToQueryBuilder(field, Wildcard, value))
+		} else {
+			exact = append(exact, value)
+		}
+	}
+	switch len(exact) {
+	case 0:
+	case 1:
+		clauses = append(clauses, appendToQueryBuilder(field, Term, exact[0]))
+	default:
+		clauses = append(clauses, appendToQueryBuilder(field, Terms, exact))
+	}
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should":               clauses,
+			"minimum_should_match": 1,
+		}}
+}
@@ -0,0 +1,131 @@
+package elastic
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/configuration"
+	"go.uber.org/zap"
+)
+
+// certificates holds the client key pair and the CA bundle of the
+// Elasticsearch connection. The operator rotates the mounted files, so they
+// are read again whenever they change before a connection is opened.
+type certificates struct {
+	log      *zap.Logger
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mutex    sync.Mutex
+	versions map[string]fileVersion
+	cert     *tls.Certificate
+	roots    *x509.CertPool
+}
+
+// fileVersion identifies the content of a file without reading it.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// newTLSDialer returns the function opening the TLS connections to
+// Elasticsearch: the client presents the key pair when UseTLS is set, and the
+// server is verified against the CA bundle when there is one, the system CAs
+// otherwise. Every connection uses the certificates of the files at the time.
+func newTLSDialer(log *zap.Logger, config *configuration.ElasticsearchConfig, dialer *net.Dialer) (func(ctx context.Context, network string, address string) (net.Conn, error), error) {
+	files := &certificates{log: log, caFile: config.EsCA, versions: map[string]fileVersion{}}
+	if config.UseTLS {
+		files.certFile, files.keyFile = config.EsCert, config.EsKey
+	}
+	err := files.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		_, roots := files.current()
+		serverName := config.EsServerName
+		if len(serverName) == 0 {
+			serverName, _, _ = net.SplitHostPort(address)
+		}
+		tlsConfig := &tls.Config{ServerName: serverName, RootCAs: roots}
+		if len(files.certFile) > 0 {
+			tlsConfig.GetClientCertificate = files.clientCertificate
+		}
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+		return tlsDialer.DialContext(ctx, network, address)
+	}, nil
+}
+
+// reload reads the files again when they changed since they were last read.
+func (files *certificates) reload() error {
+	files.mutex.Lock()
+	defer files.mutex.Unlock()
+
+	changed := false
+	versions := map[string]fileVersion{}
+	for _, path := range []string{files.certFile, files.keyFile, files.caFile} {
+		if len(path) == 0 {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		versions[path] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+		changed = changed || versions[path] != files.versions[path]
+	}
+	if !changed {
+		return nil
+	}
+
+	var cert *tls.Certificate
+	if len(files.certFile) > 0 {
+		keyPair, err := tls.LoadX509KeyPair(files.certFile, files.keyFile)
+		if err != nil {
+			return err
+		}
+		cert = &keyPair
+	}
+	var roots *x509.CertPool
+	if len(files.caFile) > 0 {
+		ca, err := ioutil.ReadFile(files.caFile)
+		if err != nil {
+			return err
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(ca) {
+			return errors.New("no certificate found in " + files.caFile)
+		}
+	}
+	files.cert, files.roots, files.versions = cert, roots, versions
+	files.log.Info("loaded the Elasticsearch certificates", zap.String("cert", files.certFile), zap.String("ca", files.caFile))
+	return nil
+}
+
+// current reloads the files if needed and returns the certificates to use.
+// Files that cannot be read, typically while they are being replaced, leave
+// the previous certificates in place.
+func (files *certificates) current() (*tls.Certificate, *x509.CertPool) {
+	err := files.reload()
+	if err != nil {
+		files.log.Error("failed to reload the Elasticsearch certificates, keeping the previous ones", zap.Error(err))
+	}
+	files.mutex.Lock()
+	defer files.mutex.Unlock()
+	return files.cert, files.roots
+}
+
+// clientCertificate returns the current client key pair when Elasticsearch asks for it.
+func (files *certificates) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, _ := files.current()
+	return cert, nil
+}
@@ -0,0 +1,271 @@
+package elastic
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/configuration"
+	"go.uber.org/zap"
+)
+
+// testCA signs the certificates of a test.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	ca := &testCA{}
+	ca.cert, ca.key, ca.pem = newCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "openshift-logging"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+	return ca
+}
+
+// issue returns a key pair signed by the CA, PEM encoded.
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) (certPEM []byte, keyPEM []byte) {
+	_, key, certPEM := newCertificate(t, template, ca)
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode the key. E: %v", err)
+	}
+	return certPEM, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+// newCertificate signs the template with the CA, or self signs it when there is none.
+func newCertificate(t *testing.T, template *x509.Certificate, ca *testCA) (*x509.Certificate, *ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate a key. E: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	parent, signer := template, key
+	if ca != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatalf("failed to create a certificate. E: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func serverTemplate(names ...string) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "elasticsearch"},
+		DNSNames:    names,
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+}
+
+func clientTemplate() *x509.Certificate {
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "system.logging.fluentd"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+}
+
+// tlsElasticsearch answers the cluster health requests of clients presenting
+// a certificate of its client CA. Both the server certificate and the client
+// CA can be replaced, like when the operator rotates them.
+type tlsElasticsearch struct {
+	server    *httptest.Server
+	mutex     sync.Mutex
+	cert      tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+func newTLSElasticsearch(t *testing.T, serverCA *testCA, server *x509.Certificate, clientCA *testCA) *tlsElasticsearch {
+	fake := &tlsElasticsearch{}
+	fake.rotate(t, serverCA, server, clientCA)
+	fake.server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"cluster_name":"elasticsearch","status":"green"}`))
+	}))
+	fake.server.TLS = &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			fake.mutex.Lock()
+			defer fake.mutex.Unlock()
+			return &tls.Config{
+				Certificates: []tls.Certificate{fake.cert},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    fake.clientCAs,
+			}, nil
+		},
+	}
+	fake.server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	fake.server.Config.SetKeepAlivesEnabled(false) // every request is a new handshake
+	fake.server.StartTLS()
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (fake *tlsElasticsearch) rotate(t *testing.T, serverCA *testCA, server *x509.Certificate, clientCA *testCA) {
+	certPEM, keyPEM := serverCA.issue(t, server)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load the server certificate. E: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCA.cert)
+	fake.mutex.Lock()
+	fake.cert, fake.clientCAs = cert, clientCAs
+	fake.mutex.Unlock()
+}
+
+// writeCertificates writes the client key pair and the CA bundle where the
+// repository reads them. The modification time moves forward on every write,
+// as the files may be rewritten within the resolution of the file system.
+func writeCertificates(t *testing.T, config *configuration.ElasticsearchConfig, serverCA *testCA, clientCA *testCA, version int) {
+	certPEM, keyPEM := clientCA.issue(t, clientTemplate())
+	modTime := time.Now().Add(time.Duration(version) * time.Minute)
+	for path, content := range map[string][]byte{config.EsCert: certPEM, config.EsKey: keyPEM, config.EsCA: serverCA.pem} {
+		err := ioutil.WriteFile(path, content, 0600)
+		if err == nil {
+			err = os.Chtimes(path, modTime, modTime)
+		}
+		if err != nil {
+			t.Fatalf("failed to write %s. E: %v", path, err)
+		}
+	}
+}
+
+func newTLSRepository(t *testing.T, config *configuration.ElasticsearchConfig) *ElasticRepository {
+	repository, err := NewElasticRepository(zap.NewNop(), config)
+	if err != nil {
+		t.Fatalf("failed to create the repository. E: %v", err)
+	}
+	return repository.(*ElasticRepository)
+}
+
+func TestTLSVerification(t *testing.T) {
+	serverCA, clientCA := newTestCA(t), newTestCA(t)
+	fake := newTLSElasticsearch(t, serverCA, serverTemplate(), clientCA)
+	dir := t.TempDir()
+	config := &configuration.ElasticsearchConfig{
+		EsAddress: fake.server.URL,
+		UseTLS:    true,
+		EsCert:    filepath.Join(dir, "tls.crt"),
+		EsKey:     filepath.Join(dir, "tls.key"),
+		EsCA:      filepath.Join(dir, "ca-bundle.crt"),
+	}
+	writeCertificates(t, config, serverCA, clientCA, 0)
+
+	if !newTLSRepository(t, config).CheckReadiness(context.Background()) {
+		t.Errorf("expected the server to be verified with the CA bundle")
+	}
+
+	withoutCA := *config
+	withoutCA.EsCA = ""
+	if newTLSRepository(t, &withoutCA).CheckReadiness(context.Background()) {
+		t.Errorf("expected the server not to be trusted without the CA bundle")
+	}
+
+	withoutClientCert := *config
+	withoutClientCert.UseTLS = false
+	if newTLSRepository(t, &withoutClientCert).CheckReadiness(context.Background()) {
+		t.Errorf("expected the server to reject the client without a certificate")
+	}
+
+	wrongCA := *config
+	wrongCA.EsCA = filepath.Join(dir, "wrong-ca.crt")
+	_ = ioutil.WriteFile(wrongCA.EsCA, newTestCA(t).pem, 0600)
+	if newTLSRepository(t, &wrongCA).CheckReadiness(context.Background()) {
+		t.Errorf("expected the server not to be trusted with another CA")
+	}
+
+	_ = ioutil.WriteFile(wrongCA.EsCA, []byte("not a certificate"), 0600)
+	_, err := NewElasticRepository(zap.NewNop(), &wrongCA)
+	if err == nil {
+		t.Errorf("expected an invalid CA bundle to be rejected")
+	}
+}
+
+func TestTLSServerName(t *testing.T) {
+	serverCA, clientCA := newTestCA(t), newTestCA(t)
+	template := serverTemplate("elasticsearch.openshift-logging.svc")
+	template.IPAddresses = nil
+	fake := newTLSElasticsearch(t, serverCA, template, clientCA)
+	dir := t.TempDir()
+	config := &configuration.ElasticsearchConfig{
+		EsAddress: fake.server.URL,
+		UseTLS:    true,
+		EsCert:    filepath.Join(dir, "tls.crt"),
+		EsKey:     filepath.Join(dir, "tls.key"),
+		EsCA:      filepath.Join(dir, "ca-bundle.crt"),
+	}
+	writeCertificates(t, config, serverCA, clientCA, 0)
+
+	if newTLSRepository(t, config).CheckReadiness(context.Background()) {
+		t.Errorf("expected the certificate not to be valid for the IP address")
+	}
+	config.EsServerName = "elasticsearch.openshift-logging.svc"
+	if !newTLSRepository(t, config).CheckReadiness(context.Background()) {
+		t.Errorf("expected the certificate to be valid for the server name")
+	}
+}
+
+func TestTLSRotation(t *testing.T) {
+	serverCA, clientCA := newTestCA(t), newTestCA(t)
+	fake := newTLSElasticsearch(t, serverCA, serverTemplate(), clientCA)
+	dir := t.TempDir()
+	config := &configuration.ElasticsearchConfig{
+		EsAddress: fake.server.URL,
+		UseTLS:    true,
+		EsCert:    filepath.Join(dir, "tls.crt"),
+		EsKey:     filepath.Join(dir, "tls.key"),
+		EsCA:      filepath.Join(dir, "ca-bundle.crt"),
+	}
+	writeCertificates(t, config, serverCA, clientCA, 0)
+	repository := newTLSRepository(t, config)
+	if !repository.CheckReadiness(context.Background()) {
+		t.Fatalf("expected the repository to connect")
+	}
+
+	newServerCA, newClientCA := newTestCA(t), newTestCA(t)
+	fake.rotate(t, newServerCA, serverTemplate(), newClientCA)
+	if repository.CheckReadiness(context.Background()) {
+		t.Fatalf("expected the previous certificates to be rejected")
+	}
+
+	writeCertificates(t, config, newServerCA, newClientCA, 1)
+	if !repository.CheckReadiness(context.Background()) {
+		t.Errorf("expected the rotated certificates to be used without restarting")
+	}
+
+	// a key pair that does not match, as while the files are being replaced,
+	// leaves the loaded certificates in place
+	otherCert, _ := newClientCA.issue(t, clientTemplate())
+	_ = ioutil.WriteFile(config.EsCert, otherCert, 0600)
+	later := time.Now().Add(2 * time.Minute)
+	_ = os.Chtimes(config.EsCert, later, later)
+	if !repository.CheckReadiness(context.Background()) {
+		t.Errorf("expected the previous certificates to be kept while the new ones are invalid")
+	}
+}
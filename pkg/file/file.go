@@ -0,0 +1,249 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ViaQ/log-exploration-api/pkg/configuration"
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"go.uber.org/zap"
+)
+
+// FileRepository serves logs from NDJSON dumps indexed in memory at startup,
+// such as the output of the export endpoint or of an Elasticsearch scroll.
+// Every line is either a search hit with _index, _id and _source, or a bare
+// ViaQ record.
+type FileRepository struct {
+	entries []logs.LogEntry
+	log     *zap.Logger
+}
+
+func NewFileRepository(log *zap.Logger, config *configuration.FileConfig) (logs.LogsProvider, error) {
+	if len(config.Paths) == 0 {
+		return nil, errors.New("at least one NDJSON file is required")
+	}
+	repository := &FileRepository{log: log}
+	for _, path := range config.Paths {
+		entries, err := ReadEntries(path)
+		if err != nil {
+			log.Error("failed to read the logs", zap.String("file", path), zap.Error(err))
+			return nil, err
+		}
+		log.Info("logs loaded", zap.String("file", path), zap.Int("entries", len(entries)))
+		repository.entries = append(repository.entries, entries...)
+	}
+	return repository, nil
+}
+
+// ReadEntries decodes every line of an NDJSON file into a log entry.
+func ReadEntries(path string) ([]logs.LogEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []logs.LogEntry
+	reader := bufio.NewReader(f)
+	for number := 1; ; number++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			entry, decodeErr := newLogEntry(line, fmt.Sprintf("%s:%d", filepath.Base(path), number))
+			if decodeErr != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, number, decodeErr)
+			}
+			entries = append(entries, entry)
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+	}
+}
+
+// newLogEntry decodes a search hit or a bare ViaQ record. Bare records get the
+// id given and the index they would be written to by the collector.
+func newLogEntry(line []byte, id string) (logs.LogEntry, error) {
+	var hit struct {
+		Index  string          `json:"_index"`
+		ID     string          `json:"_id"`
+		Source json.RawMessage `json:"_source"`
+	}
+	err := json.Unmarshal(line, &hit)
+	if err != nil {
+		return logs.LogEntry{}, err
+	}
+	if hit.Source == nil {
+		hit.Source = line
+		hit.ID = id
+		hit.Index, err = recordIndex(line)
+		if err != nil {
+			return logs.LogEntry{}, err
+		}
+		line, _ = json.Marshal(map[string]interface{}{
+			"_index":  hit.Index,
+			"_type":   "_doc",
+			"_id":     hit.ID,
+			"_source": hit.Source,
+		})
+	} else if len(hit.ID) == 0 {
+		hit.ID = id
+	}
+	entry, err := logs.NewLogEntry(hit.ID, hit.Index, hit.Source)
+	if err != nil {
+		return logs.LogEntry{}, err
+	}
+	entry.Hit = string(line)
+	return entry, nil
+}
+
+// logTypes maps the log_type field set by the collector to the index names.
+var logTypes = map[string]string{
+	"application":    "app",
+	"infrastructure": "infra",
+	"audit":          "audit",
+}
+
+// recordIndex returns the index of a bare record: the one of its log_type when
+// set, otherwise infra for node logs and the logs of the default, openshift
+// and kube namespaces, and app for the others.
+func recordIndex(source []byte) (string, error) {
+	var record struct {
+		LogType    string `json:"log_type"`
+		Kubernetes *struct {
+			NamespaceName string `json:"namespace_name"`
+		} `json:"kubernetes"`
+	}
+	err := json.Unmarshal(source, &record)
+	if err != nil {
+		return "", err
+	}
+	if index, ok := logTypes[record.LogType]; ok {
+		return index, nil
+	}
+	if record.Kubernetes == nil {
+		return "infra", nil
+	}
+	namespace := record.Kubernetes.NamespaceName
+	if namespace == "default" || strings.HasPrefix(namespace, "openshift") || strings.HasPrefix(namespace, "kube") {
+		return "infra", nil
+	}
+	return "app", nil
+}
+
+func (repository *FileRepository) CheckReadiness(ctx context.Context) bool {
+	return true
+}
+
+// search returns the page of the entries picked by the filter.
+func (repository *FileRepository) search(params logs.Parameters, filter logs.Filter) (logs.Page, error) {
+	err := params.Validate()
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.Page{}, err
+	}
+	return logs.Paginate(params, logs.Select(repository.entries, filter)), nil
+}
+
+func (repository *FileRepository) FilterLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return repository.search(params, params.Filter())
+}
+
+func (repository *FileRepository) FilterContainerLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return repository.search(params, logs.ContainerFilter(params))
+}
+
+func (repository *FileRepository) FilterPodLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return repository.search(params, logs.PodFilter(params))
+}
+
+func (repository *FileRepository) FilterNamespaceLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return repository.search(params, logs.NamespaceFilter(params))
+}
+
+func (repository *FileRepository) Logs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return repository.search(params, logs.LogsFilter(params))
+}
+
+func (repository *FileRepository) FilterLabelLogs(ctx context.Context, params logs.Parameters, labelList []string) (logs.Page, error) {
+	return repository.search(params, logs.LabelFilter(params, labelList))
+}
+
+func (repository *FileRepository) FilterEntityLogs(ctx context.Context, params logs.Parameters, kind string, name string) (logs.Page, error) {
+	selector, err := logs.NewWorkloadSelector(kind, name)
+	if err != nil {
+		repository.log.Error("Invalid entity:", zap.Error(err))
+		return logs.Page{}, err
+	}
+	return repository.search(params, logs.EntityFilter(params, selector))
+}
+
+func (repository *FileRepository) Search(ctx context.Context, params logs.Parameters, filter logs.Condition) (logs.Page, error) {
+	err := params.Validate()
+	if err == nil {
+		err = filter.Validate()
+	}
+	if err != nil {
+		return logs.Page{}, err
+	}
+	return repository.search(params, logs.SearchFilter(params, filter))
+}
+
+// Histogram counts the logs matching the filter parameters of FilterLogs per time bucket.
+func (repository *FileRepository) Histogram(ctx context.Context, params logs.Parameters, options logs.HistogramOptions) (logs.Histogram, error) {
+	err := params.Validate()
+	if err == nil {
+		err = options.Validate()
+	}
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.Histogram{}, err
+	}
+	return logs.CountHistogram(logs.Select(repository.entries, params.Filter()), params, options)
+}
+
+// Values lists the most frequent values of a field in the logs matching the
+// filter parameters of FilterLogs.
+func (repository *FileRepository) Values(ctx context.Context, params logs.Parameters, field string, options logs.ValuesOptions) (logs.FieldValues, error) {
+	err := params.Validate()
+	if err == nil {
+		err = options.Validate(field)
+	}
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.FieldValues{}, err
+	}
+	return logs.CountValues(logs.Select(repository.entries, params.Filter()), field, options), nil
+}
+
+// Context returns the entry identified by id and the entries its container
+// wrote right before and after it.
+func (repository *FileRepository) Context(ctx context.Context, params logs.Parameters, id string, options logs.ContextOptions) (logs.LogContext, error) {
+	err := options.Validate()
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.LogContext{}, err
+	}
+	return logs.ContextEntries(params, repository.entries, id, options)
+}
+
+// Export hands the logs matching the filter parameters of FilterLogs over in batches.
+func (repository *FileRepository) Export(ctx context.Context, params logs.Parameters, handle func([]logs.LogEntry) error) error {
+	err := params.Validate()
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return err
+	}
+	return logs.ExportEntries(ctx, params, logs.Select(repository.entries, params.Filter()), handle)
+}
@@ -0,0 +1,190 @@
+package file
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ViaQ/log-exploration-api/pkg/configuration"
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/ViaQ/log-exploration-api/pkg/logs/logstest"
+	"go.uber.org/zap"
+)
+
+// records are bare ViaQ records, next to the search hits of the test mapping.
+const records = `{"@timestamp":"2021-03-18T06:42:00.000000+00:00","message":"GET /healthz connection refused","level":"error","kubernetes":{"namespace_name":"my-project","pod_name":"web-5d8c7b9f6d-abcde","container_name":"web","flat_labels":["app=web","pod-template-hash=5d8c7b9f6d"]}}
+
+{"@timestamp":"2021-03-18T06:42:01.000000+00:00","message":"user login","log_type":"audit","hostname":"ip-10-0-162-9"}
+`
+
+func newTestRepository(t *testing.T) *FileRepository {
+	path := filepath.Join(t.TempDir(), "records.ndjson")
+	err := ioutil.WriteFile(path, []byte(records), 0600)
+	if err != nil {
+		t.Fatalf("failed to write the records. E: %v", err)
+	}
+	repository, err := NewFileRepository(zap.L(), &configuration.FileConfig{Paths: []string{"../../test-logs-mapping.json", path}})
+	if err != nil {
+		t.Fatalf("failed to create the repository. E: %v", err)
+	}
+	return repository.(*FileRepository)
+}
+
+func TestFilters(t *testing.T) {
+	repository := newTestRepository(t)
+	tests := []struct {
+		TestName string
+		Params   logs.Parameters
+		Count    int
+	}{
+		{"No filter", logs.Parameters{}, 1000},
+		{"Namespace", logs.Parameters{Namespace: "openshift-oauth-apiserver"}, 18},
+		{"Pod and container", logs.Parameters{Podname: "kube-apiserver-ip-10-0-162-9.ec2.internal", ContainerName: "kube-apiserver"}, 12},
+		{"Level", logs.Parameters{Level: "error"}, 1},
+		{"Index alias", logs.Parameters{Index: "infra", MaxLogs: "1000"}, 1000},
+		{"Rollover index", logs.Parameters{Index: "infra-000001", Namespace: "openshift-etcd"}, 3},
+		{"Index of bare records", logs.Parameters{Index: "audit"}, 1},
+		{"Inclusive time range", logs.Parameters{StartTime: "2021-03-18T06:41:21.653456Z", FinishTime: "2021-03-18T06:41:21.653456Z", Namespace: "openshift-oauth-apiserver"}, 2},
+		{"Message query", logs.Parameters{Query: `"Connection refused" -login`}, 1},
+		{"Visible namespaces", logs.Parameters{Namespaces: []string{"my-project", "openshift-etcd"}}, 4},
+		{"No visible namespace", logs.Parameters{Namespaces: []string{}}, 0},
+	}
+	for _, tt := range tests {
+		t.Log("Running:", tt.TestName)
+		page, err := repository.FilterLogs(context.Background(), tt.Params)
+		if err != nil {
+			t.Errorf("unexpected error. E: %v", err)
+		}
+		if len(page.Logs) != tt.Count {
+			t.Errorf("expected %d logs, got %d", tt.Count, len(page.Logs))
+		}
+	}
+
+	_, err := repository.FilterLogs(context.Background(), logs.Parameters{Order: "random"})
+	if err == nil || err.Error() != logs.InvalidOrder().Error() {
+		t.Errorf("expected %v, got %v", logs.InvalidOrder(), err)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	repository := newTestRepository(t)
+	tests := []struct {
+		TestName string
+		Query    func() (logs.Page, error)
+		Count    int
+	}{
+		{"Empty namespace", func() (logs.Page, error) {
+			return repository.FilterNamespaceLogs(context.Background(), logs.Parameters{})
+		}, 0},
+		{"Labels", func() (logs.Page, error) {
+			return repository.FilterLabelLogs(context.Background(), logs.Parameters{}, []string{"app=web", "pod-template-hash=5d8c7b9f6d"})
+		}, 1},
+		{"Missing label", func() (logs.Page, error) {
+			return repository.FilterLabelLogs(context.Background(), logs.Parameters{}, []string{"app=web", "tier=db"})
+		}, 0},
+		{"Deployment", func() (logs.Page, error) {
+			return repository.FilterEntityLogs(context.Background(), logs.Parameters{Namespace: "my-project"}, "deployment", "web")
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Log("Running:", tt.TestName)
+		page, err := tt.Query()
+		if err != nil {
+			t.Errorf("unexpected error. E: %v", err)
+		}
+		if len(page.Logs) != tt.Count {
+			t.Errorf("expected %d logs, got %d", tt.Count, len(page.Logs))
+		}
+	}
+}
+
+func TestBareRecords(t *testing.T) {
+	repository := newTestRepository(t)
+	page, err := repository.FilterLogs(context.Background(), logs.Parameters{Namespace: "my-project"})
+	if err != nil {
+		t.Fatalf("unexpected error. E: %v", err)
+	}
+	if len(page.Logs) != 1 {
+		t.Fatalf("expected 1 log, got %d", len(page.Logs))
+	}
+	entry := page.Logs[0]
+	if entry.ID != "records.ndjson:1" || entry.Index != "app" || entry.Level != "error" || entry.Kubernetes.ContainerName != "web" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	expectedHit := `{"_id":"records.ndjson:1","_index":"app","_source":` + `{"@timestamp":"2021-03-18T06:42:00.000000+00:00","message":"GET /healthz connection refused","level":"error","kubernetes":{"namespace_name":"my-project","pod_name":"web-5d8c7b9f6d-abcde","container_name":"web","flat_labels":["app=web","pod-template-hash=5d8c7b9f6d"]}}` + `,"_type":"_doc"}`
+	if entry.Hit != expectedHit {
+		t.Errorf("expected hit %s, got %s", expectedHit, entry.Hit)
+	}
+
+	page, err = repository.FilterLogs(context.Background(), logs.Parameters{Index: "audit"})
+	if err != nil || len(page.Logs) != 1 || page.Logs[0].ID != "records.ndjson:3" {
+		t.Errorf("expected the audit record on line 3, got %+v, %v", page.Logs, err)
+	}
+
+	_, err = NewFileRepository(zap.L(), &configuration.FileConfig{Paths: []string{"missing.ndjson"}})
+	if err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+	path := filepath.Join(t.TempDir(), "invalid.ndjson")
+	_ = ioutil.WriteFile(path, []byte("{}\nnot json\n"), 0600)
+	_, err = NewFileRepository(zap.L(), &configuration.FileConfig{Paths: []string{path}})
+	if err == nil || err.Error()[:len(path)+2] != path+":2" {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
+
+func TestHistogramValuesAndExport(t *testing.T) {
+	repository := newTestRepository(t)
+	params := logs.Parameters{Namespace: "openshift-oauth-apiserver"}
+
+	histogram, err := repository.Histogram(context.Background(), params, logs.HistogramOptions{Interval: "1m", SplitBy: "level"})
+	if err != nil {
+		t.Fatalf("unexpected error. E: %v", err)
+	}
+	if histogram.Interval != "1m" || len(histogram.Buckets) != 1 || histogram.Buckets[0].Count != 18 ||
+		!reflect.DeepEqual(histogram.Buckets[0].Split, map[string]int64{"unknown": 18}) {
+		t.Errorf("unexpected histogram %+v", histogram)
+	}
+	histogram, err = repository.Histogram(context.Background(), logs.Parameters{Namespace: "missing"}, logs.HistogramOptions{Buckets: 10})
+	if err != nil || len(histogram.Buckets) != 0 {
+		t.Errorf("expected no bucket, got %+v, %v", histogram, err)
+	}
+
+	values, err := repository.Values(context.Background(), logs.Parameters{Index: "infra"}, "namespace", logs.ValuesOptions{Prefix: "openshift-kube", Size: 2})
+	if err != nil {
+		t.Fatalf("unexpected error. E: %v", err)
+	}
+	expectedValues := []logs.FieldValue{{Value: "openshift-kube-apiserver", Count: 16}, {Value: "openshift-kube-scheduler", Count: 16}}
+	if !reflect.DeepEqual(values.Values, expectedValues) {
+		t.Errorf("expected values %v, got %v", expectedValues, values.Values)
+	}
+
+	var exported []logs.LogEntry
+	err = repository.Export(context.Background(), logs.Parameters{Index: "infra"}, func(batch []logs.LogEntry) error {
+		exported = append(exported, batch...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error. E: %v", err)
+	}
+	if len(exported) != 1035 {
+		t.Errorf("expected 1035 logs, got %d", len(exported))
+	}
+	err = repository.Export(context.Background(), logs.Parameters{Namespace: "missing"}, func(entries []logs.LogEntry) error {
+		t.Errorf("expected no logs, got %d", len(entries))
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected exporting no logs to succeed, got %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	repository, err := NewFileRepository(zap.L(), &configuration.FileConfig{Paths: []string{"../../test-logs-mapping.json"}})
+	if err != nil {
+		t.Fatalf("failed to create the repository. E: %v", err)
+	}
+	logstest.TestProvider(t, repository)
+}
@@ -0,0 +1,87 @@
+package logs
+
+const (
+	DefaultContextSize = 10
+	MaxContextSize     = 100
+)
+
+// ContextOptions tells how many entries surround the entry of a log context.
+type ContextOptions struct {
+	Size int `form:"size"` // maximum number of entries before and after the entry
+}
+
+// LogContext is a log entry along with the entries written right before and
+// after it by the same container, both oldest first.
+type LogContext struct {
+	Before []LogEntry
+	Entry  LogEntry
+	After  []LogEntry
+}
+
+// Validate checks the options and fills in the default size.
+func (options *ContextOptions) Validate() error {
+	if options.Size == 0 {
+		options.Size = DefaultContextSize
+	}
+	if options.Size < 0 || options.Size > MaxContextSize {
+		return InvalidContextSize()
+	}
+	return nil
+}
+
+// Visibility returns the parameters restricting which logs a log context may
+// show: the namespace and index asked for, which the caller may read, and
+// the visible namespaces and indices.
+func (params Parameters) Visibility() Parameters {
+	return Parameters{Namespace: params.Namespace, Index: params.Index, Namespaces: params.Namespaces, Indices: params.Indices, Token: params.Token}
+}
+
+// Identifies reports whether id is the document id or the viaq_msg_id of the entry.
+func (entry LogEntry) Identifies(id string) bool {
+	return len(id) > 0 && (entry.ID == id || entry.ViaqMsgID == id)
+}
+
+// SameStream reports whether both entries were written by the same container.
+// Logs without Kubernetes metadata, such as node logs, have no stream.
+func SameStream(a LogEntry, b LogEntry) bool {
+	ka, kb := a.KubernetesMetadata(), b.KubernetesMetadata()
+	return len(ka.PodName) > 0 && ka.NamespaceName == kb.NamespaceName && ka.PodName == kb.PodName &&
+		ka.ContainerName == kb.ContainerName
+}
+
+// ContextEntries returns the context of the entry identified by id among the
+// entries visible with the parameters, like the search_after queries of the
+// Elasticsearch provider, or NotFoundError when there is no such entry. Only
+// the namespace and index of the parameters and the visible namespaces and
+// indices apply.
+func ContextEntries(params Parameters, entries []LogEntry, id string, options ContextOptions) (LogContext, error) {
+	visible := params.Visibility().Filter()
+	var found *LogEntry
+	for i := range entries {
+		if entries[i].Identifies(id) && visible(entries[i]) {
+			found = &entries[i]
+			break
+		}
+	}
+	if found == nil {
+		return LogContext{}, NotFoundError()
+	}
+	entry := *found
+	stream := Select(entries, func(other LogEntry) bool {
+		return other.ID == entry.ID || (SameStream(entry, other) && visible(other))
+	})
+	SortEntries("asc", stream)
+
+	position := 0
+	for position < len(stream) && stream[position].ID != entry.ID {
+		position++
+	}
+	start, end := position-options.Size, position+1+options.Size
+	if start < 0 {
+		start = 0
+	}
+	if end > len(stream) {
+		end = len(stream)
+	}
+	return LogContext{Before: stream[start:position], Entry: entry, After: stream[position+1 : end]}, nil
+}
@@ -0,0 +1,52 @@
+package logs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// Cursor identifies the position of the last log entry of a page. Results
+// are ordered by timestamp, then by index and then by document id, which is
+// only unique within an index, so the three are enough to resume a query
+// right after that entry.
+type Cursor struct {
+	Timestamp time.Time `json:"t"`
+	Index     string    `json:"index,omitempty"`
+	ID        string    `json:"id"`
+}
+
+// EntryCursor returns the cursor pointing right after the entry.
+func EntryCursor(entry LogEntry) Cursor {
+	return Cursor{Timestamp: entry.Timestamp, Index: entry.Index, ID: entry.ID}
+}
+
+// Precedes reports whether the cursor comes before an entry sharing its
+// timestamp. Cursors handed out before the index was part of them sort
+// before every index.
+func (c Cursor) Precedes(entry LogEntry) bool {
+	if c.Index != entry.Index {
+		return c.Index < entry.Index
+	}
+	return c.ID < entry.ID
+}
+
+// Encode returns the opaque string representation of the cursor handed out to clients.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes a cursor previously returned by Encode.
+func ParseCursor(value string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, InvalidCursor()
+	}
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || cursor.Timestamp.IsZero() {
+		return cursor, InvalidCursor()
+	}
+	return cursor, nil
+}
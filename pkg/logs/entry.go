@@ -0,0 +1,71 @@
+package logs
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LogEntry is a single log record in the ViaQ data model.
+type LogEntry struct {
+	ID         string              `json:"id"`
+	Index      string              `json:"index"`
+	Timestamp  time.Time           `json:"timestamp"`
+	Message    string              `json:"message"`
+	Level      string              `json:"level,omitempty"`
+	Hostname   string              `json:"hostname,omitempty"`
+	ViaqMsgID  string              `json:"viaq_msg_id,omitempty"`
+	Kubernetes *KubernetesMetadata `json:"kubernetes,omitempty"`
+	Source     json.RawMessage     `json:"source,omitempty"`
+	// Hit is the entry as served by the v1 API, usually the JSON encoded search hit.
+	Hit string `json:"-"`
+}
+
+// KubernetesMetadata describes the container that emitted a log entry.
+type KubernetesMetadata struct {
+	NamespaceName  string            `json:"namespace_name,omitempty"`
+	PodName        string            `json:"pod_name,omitempty"`
+	ContainerName  string            `json:"container_name,omitempty"`
+	ContainerImage string            `json:"container_image,omitempty"`
+	Host           string            `json:"host,omitempty"`
+	PodID          string            `json:"pod_id,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	FlatLabels     []string          `json:"flat_labels,omitempty"`
+}
+
+// viaqRecord holds the fields of a ViaQ document that are copied into a LogEntry.
+type viaqRecord struct {
+	Timestamp  interface{}         `json:"@timestamp"`
+	Message    string              `json:"message"`
+	Level      string              `json:"level"`
+	Hostname   string              `json:"hostname"`
+	ViaqMsgID  string              `json:"viaq_msg_id"`
+	Kubernetes *KubernetesMetadata `json:"kubernetes"`
+}
+
+// NewLogEntry builds a LogEntry from the id, index and raw source of a ViaQ
+// document. A timestamp that is not an RFC 3339 string is left zero rather
+// than failing the entry, and with it the whole page, so that the caller can
+// fill it from elsewhere, such as the sort value of a search hit.
+func NewLogEntry(id string, index string, source []byte) (LogEntry, error) {
+	var record viaqRecord
+	err := json.Unmarshal(source, &record)
+	if err != nil {
+		return LogEntry{}, err
+	}
+	entry := LogEntry{
+		ID:        id,
+		Index:     index,
+		Message:   record.Message,
+		Level:     record.Level,
+		Hostname:  record.Hostname,
+		ViaqMsgID: record.ViaqMsgID,
+		Source:    json.RawMessage(source),
+	}
+	if record.Kubernetes != nil && (len(record.Kubernetes.NamespaceName) > 0 || len(record.Kubernetes.PodName) > 0) {
+		entry.Kubernetes = record.Kubernetes
+	}
+	if timestamp, ok := record.Timestamp.(string); ok {
+		entry.Timestamp, _ = time.Parse(time.RFC3339Nano, timestamp)
+	}
+	return entry, nil
+}
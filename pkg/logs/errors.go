@@ -1,13 +1,121 @@
 package logs
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorKind tells what went wrong, which decides the status code the API
+// answers with.
+type ErrorKind string
+
+const (
+	KindValidation   ErrorKind = "validation"           // the request is invalid
+	KindNotFound     ErrorKind = "not_found"            // no log matches the request
+	KindUnauthorized ErrorKind = "unauthorized"         // the token is missing or not accepted
+	KindForbidden    ErrorKind = "forbidden"            // the caller may not read the logs
+	KindUnavailable  ErrorKind = "upstream_unavailable" // the log store cannot be reached or failed
+	KindTimeout      ErrorKind = "timeout"              // the log store did not answer in time
+)
+
+// Status returns the HTTP status code of the errors of the kind.
+func (kind ErrorKind) Status() int {
+	switch kind {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindUnavailable:
+		return http.StatusBadGateway
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an error reported to the client. Code is machine-readable, such as
+// invalid_timestamp, while Message is meant for people. Callers find it with
+// errors.As, as providers may wrap it.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Err     error // the error of the log store, if any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError returns an error of the kind, whose code is the kind itself.
+func NewError(kind ErrorKind, message string, err error) error {
+	return &Error{Kind: kind, Code: string(kind), Message: message, Err: err}
+}
+
+// KindOf returns the kind of err, or an empty kind for unexpected errors.
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return ""
+}
+
+func validationError(code string, message string) error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
 
 func NotFoundError() error {
-	return errors.New("Not Found Error")
+	return NewError(KindNotFound, "no logs match the request, please check the input parameters", nil)
 }
 func InvalidTimeStamp() error {
-	return errors.New("incorrect time format: Please Enter Time in the following format YYYY-MM-DD'T'HH:mm:ss.SSS[TIMEZONE ex:'Z']")
+	return validationError("invalid_timestamp", "incorrect time format: starttime and finishtime take a time in the format YYYY-MM-DD'T'HH:mm:ss.SSS[TIMEZONE ex:'Z'], milliseconds since the epoch or date math such as now-1h/h, and since a duration such as 15m or 2h")
+}
+func InvalidTimeRange() error {
+	return validationError("invalid_time_range", "invalid time range: starttime must not be after finishtime and cannot be given along with since")
 }
 func InvalidLimit() error {
-	return errors.New("invalid \"maxlogs\" value, an integer between 0 to 1000 is required")
+	return validationError("invalid_limit", fmt.Sprintf("invalid \"maxlogs\" value, an integer between 0 to %d is required", MaxLogsLimit))
+}
+func InvalidCursor() error {
+	return validationError("invalid_cursor", "invalid \"cursor\" value, please pass the next_cursor returned by a previous request")
+}
+func InvalidQuery() error {
+	return validationError("invalid_query", "invalid \"q\" value, please close every quoted phrase")
+}
+func InvalidEntity() error {
+	return validationError("invalid_entity", "invalid entity: the kind must be one of deployment, statefulset, daemonset, job or cronjob and the name a valid Kubernetes name")
+}
+func InvalidHistogram() error {
+	return validationError("invalid_histogram", fmt.Sprintf("invalid histogram options: \"interval\" must look like 30s, 5m, 1h or 1d and split the time range in at most %d buckets, \"buckets\" must be between 1 and 1000 and \"split_by\" must be level or index", MaxHistogramBuckets))
+}
+func InvalidValueField() error {
+	return validationError("invalid_value_field", "invalid values request: the field must be one of namespace, pod, container, label or level and \"size\" must be between 1 and 1000")
+}
+func InvalidOrder() error {
+	return validationError("invalid_order", "invalid \"order\" value, asc for oldest first or desc for newest first is required")
+}
+func InvalidFilter() error {
+	return validationError("invalid_filter", "invalid filter: namespace, podname, containername and level take comma separated values, which may contain * and ? wildcards, and exclude them with exclude_<name> or <name>!=")
+}
+func InvalidFields() error {
+	return validationError("invalid_fields", "invalid fields: fields and exclude_fields take comma separated field paths such as kubernetes.pod_name, which may contain the * wildcard, and fields=compact keeps the timestamp, level, message and Kubernetes identity")
+}
+func InvalidContextSize() error {
+	return validationError("invalid_context_size", fmt.Sprintf("invalid \"size\" value, an integer between 1 and %d is required", MaxContextSize))
+}
+func InvalidSearch(path string, reason string) error {
+	return validationError("invalid_search", fmt.Sprintf("invalid search filter at %s: %s", path, reason))
+}
+func InvalidParameters(err error) error {
+	return &Error{Kind: KindValidation, Code: "invalid_parameters", Message: "invalid query parameters: " + err.Error(), Err: err}
 }
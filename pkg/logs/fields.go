@@ -0,0 +1,169 @@
+package logs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FieldFilter is the values a field of the logs must have and the values it
+// must not have, taken from the comma separated values of a filter parameter,
+// such as namespace=a,b, and of its exclusion, exclude_namespace or
+// namespace!=. Values may contain the * and ? wildcards.
+type FieldFilter struct {
+	Field   string // namespace, pod, container or level, like ValueFields
+	Include []string
+	Exclude []string
+
+	include *patterns
+	exclude *patterns
+}
+
+// filterFields are the fields of the logs the filter parameters filter on.
+var filterFields = []string{"namespace", "pod", "container", "level"}
+
+// FieldFilter returns the filter of a field, which is empty when the
+// parameters do not filter on it.
+func (params Parameters) FieldFilter(field string) FieldFilter {
+	filter := FieldFilter{Field: field}
+	switch field {
+	case "namespace":
+		filter.Include, filter.Exclude = SplitValues(params.Namespace), SplitValues(params.ExcludeNamespace)
+	case "pod":
+		filter.Include, filter.Exclude = SplitValues(params.Podname), SplitValues(params.ExcludePodname)
+	case "container":
+		filter.Include, filter.Exclude = SplitValues(params.ContainerName), SplitValues(params.ExcludeContainerName)
+	case "level":
+		filter.Include, filter.Exclude = SplitValues(params.Level), SplitValues(params.ExcludeLevel)
+	}
+	filter.include, filter.exclude = compilePatterns(filter.Include), compilePatterns(filter.Exclude)
+	return filter
+}
+
+// FieldFilters returns the filters of the fields the parameters filter on.
+func (params Parameters) FieldFilters() []FieldFilter {
+	var filters []FieldFilter
+	for _, field := range filterFields {
+		filter := params.FieldFilter(field)
+		if !filter.Empty() {
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
+
+// Exclude adds values to the exclusion of the filter parameter name, as given
+// by name!=values. It reports whether the parameter can be excluded.
+func (params *Parameters) Exclude(name string, values string) bool {
+	var exclude *string
+	switch name {
+	case "namespace":
+		exclude = &params.ExcludeNamespace
+	case "podname":
+		exclude = &params.ExcludePodname
+	case "containername":
+		exclude = &params.ExcludeContainerName
+	case "level":
+		exclude = &params.ExcludeLevel
+	default:
+		return false
+	}
+	if len(*exclude) > 0 {
+		values = *exclude + "," + values
+	}
+	*exclude = values
+	return true
+}
+
+// Empty reports whether the filter keeps every log.
+func (filter FieldFilter) Empty() bool {
+	return len(filter.Include) == 0 && len(filter.Exclude) == 0
+}
+
+// Matches reports whether a value of the field passes the filter. Like term
+// and wildcard queries, a missing value matches no value and is never excluded.
+func (filter FieldFilter) Matches(value string) bool {
+	if len(value) == 0 {
+		return len(filter.Include) == 0
+	}
+	include, exclude := filter.include, filter.exclude
+	if include == nil || exclude == nil {
+		include, exclude = compilePatterns(filter.Include), compilePatterns(filter.Exclude)
+	}
+	if len(filter.Include) > 0 && !include.matches(value) {
+		return false
+	}
+	return !exclude.matches(value)
+}
+
+// validate checks that no value is empty, as in "a,,b".
+func (filter FieldFilter) validate() error {
+	for _, value := range append(append([]string{}, filter.Include...), filter.Exclude...) {
+		if len(value) == 0 {
+			return InvalidFilter()
+		}
+	}
+	return nil
+}
+
+// SplitValues splits the comma separated values of a filter parameter. An
+// empty parameter has no value.
+func SplitValues(value string) []string {
+	if len(value) == 0 {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// IsPattern reports whether a value has wildcards: * for any characters and ? for one.
+func IsPattern(value string) bool {
+	return strings.ContainsAny(value, "*?")
+}
+
+// PatternRegexp translates a value that may have wildcards into an unanchored
+// regular expression matching the same values.
+func PatternRegexp(pattern string) string {
+	var b strings.Builder
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// patterns are values that may have wildcards, compiled once to be matched
+// against many values.
+type patterns struct {
+	exact     map[string]bool
+	wildcards []*regexp.Regexp
+}
+
+func compilePatterns(values []string) *patterns {
+	compiled := &patterns{exact: map[string]bool{}}
+	for _, value := range values {
+		if IsPattern(value) {
+			compiled.wildcards = append(compiled.wildcards, regexp.MustCompile("^(?:"+PatternRegexp(value)+")$"))
+		} else {
+			compiled.exact[value] = true
+		}
+	}
+	return compiled
+}
+
+// matches reports whether the value matches one of the patterns.
+func (compiled *patterns) matches(value string) bool {
+	if compiled.exact[value] {
+		return true
+	}
+	for _, wildcard := range compiled.wildcards {
+		if wildcard.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
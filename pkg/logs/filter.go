@@ -0,0 +1,232 @@
+package logs
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InIndex reports whether a log stored in index belongs to the index or alias
+// name, like app-000001 belongs to app.
+func InIndex(index string, name string) bool {
+	return index == name || strings.HasPrefix(index, name+"-")
+}
+
+// InIndices reports whether a log stored in index belongs to one of the names.
+func InIndices(index string, names []string) bool {
+	for _, name := range names {
+		if InIndex(index, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the entry satisfies the filter parameters of
+// FilterLogs, with the semantics of the Elasticsearch queries: exact terms or
+// wildcards, an inclusive time range, case insensitive message terms and an
+// index given either by name or by the alias of its rollover indices, such as
+// app for app-000001.
+// The parameters must have been validated.
+func (params Parameters) Matches(entry LogEntry) bool {
+	return params.Filter()(entry)
+}
+
+// Filter returns Matches as a Filter, which resolves the time range against
+// the current time once for every entry it is given.
+func (params Parameters) Filter() Filter {
+	filters := params.FieldFilters()
+	timeRange, _ := params.TimeRange(time.Now())
+	terms, _ := ParseMessageQuery(params.Query)
+	return func(entry LogEntry) bool {
+		for _, filter := range filters {
+			if !filter.Matches(entry.fieldValues(filter.Field)[0]) {
+				return false
+			}
+		}
+		if len(params.Index) > 0 && !InIndex(entry.Index, params.Index) {
+			return false
+		}
+		if params.Indices != nil && !InIndices(entry.Index, params.Indices) {
+			return false
+		}
+		if !timeRange.Contains(entry.Timestamp) {
+			return false
+		}
+		if params.Namespaces != nil && !containsString(params.Namespaces, entry.KubernetesMetadata().NamespaceName) {
+			return false
+		}
+		for _, term := range terms {
+			if !term.Matches(entry.Message) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Filter picks the log entries a LogsProvider method returns. The filters
+// below mirror the queries the Elasticsearch provider builds for every method,
+// so that providers keeping logs in memory answer exactly like it.
+type Filter func(entry LogEntry) bool
+
+// LogsFilter matches the clauses every query shares: the index, time range,
+// level, visible namespaces and message query. Logs only filters on them.
+func LogsFilter(params Parameters) Filter {
+	params.Namespace = ""
+	params.Podname = ""
+	params.ContainerName = ""
+	return params.Filter()
+}
+
+// NamespaceFilter matches the logs of the namespace of the parameters.
+func NamespaceFilter(params Parameters) Filter {
+	shared := LogsFilter(params)
+	return func(entry LogEntry) bool {
+		return shared(entry) && term(params.Namespace, entry.KubernetesMetadata().NamespaceName)
+	}
+}
+
+// PodFilter matches the logs of the pod of the parameters.
+func PodFilter(params Parameters) Filter {
+	shared := LogsFilter(params)
+	return func(entry LogEntry) bool {
+		kubernetes := entry.KubernetesMetadata()
+		return shared(entry) && term(params.Namespace, kubernetes.NamespaceName) && term(params.Podname, kubernetes.PodName)
+	}
+}
+
+// ContainerFilter matches the logs of the container of the parameters.
+func ContainerFilter(params Parameters) Filter {
+	shared := LogsFilter(params)
+	return func(entry LogEntry) bool {
+		kubernetes := entry.KubernetesMetadata()
+		return shared(entry) && term(params.Namespace, kubernetes.NamespaceName) && term(params.Podname, kubernetes.PodName) &&
+			term(params.ContainerName, kubernetes.ContainerName)
+	}
+}
+
+// LabelFilter matches the logs of the pods having every given flat label.
+func LabelFilter(params Parameters, labels []string) Filter {
+	shared := LogsFilter(params)
+	return func(entry LogEntry) bool {
+		if !shared(entry) {
+			return false
+		}
+		for _, label := range labels {
+			if !containsString(entry.KubernetesMetadata().FlatLabels, label) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// EntityFilter matches the logs of the pods of a workload in the namespace of the parameters.
+func EntityFilter(params Parameters, selector WorkloadSelector) Filter {
+	shared := LogsFilter(params)
+	return func(entry LogEntry) bool {
+		kubernetes := entry.KubernetesMetadata()
+		return shared(entry) && term(params.Namespace, kubernetes.NamespaceName) &&
+			selector.Matches(kubernetes.PodName, kubernetes.FlatLabels)
+	}
+}
+
+// term matches a field like a term query, so an empty value matches no log.
+func term(value string, field string) bool {
+	return len(value) > 0 && field == value
+}
+
+// Select returns the entries matching the filter.
+func Select(entries []LogEntry, filter Filter) []LogEntry {
+	var selected []LogEntry
+	for _, entry := range entries {
+		if filter(entry) {
+			selected = append(selected, entry)
+		}
+	}
+	return selected
+}
+
+// KubernetesMetadata returns the Kubernetes metadata of the entry, empty when it has none.
+func (entry LogEntry) KubernetesMetadata() KubernetesMetadata {
+	if entry.Kubernetes == nil {
+		return KubernetesMetadata{}
+	}
+	return *entry.Kubernetes
+}
+
+// SortEntries orders entries newest first unless the order is "asc", breaking
+// ties by index and then by id like the sort of the Elasticsearch queries.
+func SortEntries(order string, entries []LogEntry) {
+	ascending := order == "asc"
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Timestamp.Equal(entries[j].Timestamp) {
+			return entries[i].Timestamp.After(entries[j].Timestamp) != ascending
+		}
+		return EntryCursor(entries[i]).Precedes(entries[j])
+	})
+}
+
+// Paginate sorts the matching entries and cuts the page described by the
+// cursor and maxlogs parameters out of them, keeping the fields of the
+// projection of the parameters. The parameters must have been validated.
+func Paginate(params Parameters, entries []LogEntry) Page {
+	SortEntries(params.Order, entries)
+	if len(params.Cursor) > 0 {
+		cursor, _ := ParseCursor(params.Cursor)
+		ascending := params.Order == "asc"
+		position := sort.Search(len(entries), func(i int) bool {
+			if entries[i].Timestamp.Equal(cursor.Timestamp) {
+				return cursor.Precedes(entries[i])
+			}
+			return entries[i].Timestamp.After(cursor.Timestamp) == ascending
+		})
+		entries = entries[position:]
+	}
+	maxEntries := params.PageSize()
+
+	var page Page
+	for i, entry := range entries {
+		if i == maxEntries {
+			page.NextCursor = page.EndCursor
+			break
+		}
+		page.Logs = append(page.Logs, entry)
+		page.EndCursor = EntryCursor(entry).Encode()
+	}
+	page.Logs = ProjectEntries(page.Logs, params.Projection())
+	return page
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportEntries sorts the entries in the order of the parameters and hands
+// them over to handle in batches, keeping the fields of the projection of the
+// parameters, like the Export method of a provider.
+func ExportEntries(ctx context.Context, params Parameters, entries []LogEntry, handle func([]LogEntry) error) error {
+	SortEntries(params.Order, entries)
+	projection := params.Projection()
+	for start := 0; start < len(entries); start += ExportBatchSize {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		end := start + ExportBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		err := handle(ProjectEntries(entries[start:end], projection))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package logs
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const DefaultHistogramBuckets = 50
+
+// MaxHistogramBuckets is the most buckets a histogram may have, like the
+// search.max_buckets setting of Elasticsearch, so that a small interval over
+// a long time range cannot exhaust the memory.
+const MaxHistogramBuckets = 10000
+
+// HistogramOptions controls how logs are counted over time.
+type HistogramOptions struct {
+	Interval string `form:"interval"` // fixed bucket width such as 30s, 5m, 1h or 1d
+	Buckets  int    `form:"buckets"`  // number of buckets to aim for when no interval is given
+	SplitBy  string `form:"split_by"` // "level" or "index" to count every value separately
+}
+
+// Histogram is the number of logs per time bucket.
+type Histogram struct {
+	Interval string            `json:"interval"`
+	Buckets  []HistogramBucket `json:"buckets"`
+}
+
+// HistogramBucket counts the logs from Timestamp until the start of the next bucket.
+type HistogramBucket struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Count     int64            `json:"count"`
+	Split     map[string]int64 `json:"split,omitempty"`
+}
+
+var intervalFormat = regexp.MustCompile(`^([1-9][0-9]*)(ms|s|m|h|d)$`)
+
+var intervalUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+}
+
+// autoIntervals are the bucket widths picked when no interval is given.
+var autoIntervals = []string{"1s", "5s", "10s", "30s", "1m", "5m", "10m", "30m", "1h", "3h", "12h", "1d", "7d", "30d", "365d"}
+
+// Validate checks the options and fills in the default number of buckets.
+func (options *HistogramOptions) Validate() error {
+	if len(options.Interval) > 0 {
+		if _, err := ParseInterval(options.Interval); err != nil {
+			return err
+		}
+	}
+	if options.Buckets == 0 {
+		options.Buckets = DefaultHistogramBuckets
+	}
+	if options.Buckets < 0 || options.Buckets > 1000 {
+		return InvalidHistogram()
+	}
+	if options.SplitBy != "" && options.SplitBy != "level" && options.SplitBy != "index" {
+		return InvalidHistogram()
+	}
+	return nil
+}
+
+// ParseInterval parses an interval in the Elasticsearch fixed_interval format.
+func ParseInterval(interval string) (time.Duration, error) {
+	match := intervalFormat.FindStringSubmatch(interval)
+	if match == nil {
+		return 0, InvalidHistogram()
+	}
+	value, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, InvalidHistogram()
+	}
+	return time.Duration(value) * intervalUnits[match[2]], nil
+}
+
+// CheckBuckets checks that buckets of the given width aligned on the epoch
+// cover the time from one time to the other in at most MaxHistogramBuckets.
+// Zero times leave the range open, which is not checked.
+func CheckBuckets(interval time.Duration, from time.Time, to time.Time) error {
+	if from.IsZero() || to.IsZero() {
+		return nil
+	}
+	first := from.UnixNano() - from.UnixNano()%int64(interval)
+	last := to.UnixNano() - to.UnixNano()%int64(interval)
+	if (last-first)/int64(interval) >= MaxHistogramBuckets {
+		return InvalidHistogram()
+	}
+	return nil
+}
+
+// AutoInterval returns the smallest usual interval splitting the time range into
+// at most the given number of buckets.
+func AutoInterval(from time.Time, to time.Time, buckets int) string {
+	for _, interval := range autoIntervals {
+		width, _ := ParseInterval(interval)
+		if int(to.Sub(from)/width)+1 <= buckets {
+			return interval
+		}
+	}
+	return autoIntervals[len(autoIntervals)-1]
+}
+
+// CountBuckets counts the entries in buckets of the given width aligned on the
+// epoch, like an Elasticsearch date_histogram, including empty buckets between
+// the first and the last entry. Non-zero bounds extend the buckets to cover
+// them, like extended_bounds.
+func CountBuckets(entries []LogEntry, interval time.Duration, splitBy string, from time.Time, to time.Time) ([]HistogramBucket, error) {
+	counts := map[int64]*HistogramBucket{}
+	var buckets []HistogramBucket
+	for _, entry := range entries {
+		key := entry.Timestamp.UnixNano() - entry.Timestamp.UnixNano()%int64(interval)
+		bucket, ok := counts[key]
+		if !ok {
+			bucket = &HistogramBucket{Timestamp: time.Unix(0, key).UTC()}
+			counts[key] = bucket
+		}
+		bucket.Count++
+		if len(splitBy) > 0 {
+			value := entry.Level
+			if splitBy == "index" {
+				value = entry.Index
+			}
+			if bucket.Split == nil {
+				bucket.Split = map[string]int64{}
+			}
+			bucket.Split[value]++
+		}
+	}
+	for _, bucket := range counts {
+		buckets = append(buckets, *bucket)
+	}
+	return FillBuckets(buckets, interval, from, to)
+}
+
+// FillBuckets sorts buckets aligned on the epoch and adds the empty buckets
+// missing between them and up to the non-zero bounds. It fails with
+// InvalidHistogram rather than fill more than MaxHistogramBuckets.
+func FillBuckets(buckets []HistogramBucket, interval time.Duration, from time.Time, to time.Time) ([]HistogramBucket, error) {
+	counts := map[int64]HistogramBucket{}
+	var keys []int64
+	for _, bucket := range buckets {
+		counts[bucket.Timestamp.UnixNano()] = bucket
+		keys = append(keys, bucket.Timestamp.UnixNano())
+	}
+	for _, bound := range []time.Time{from, to} {
+		if !bound.IsZero() {
+			keys = append(keys, bound.UnixNano()-bound.UnixNano()%int64(interval))
+		}
+	}
+	if len(keys) == 0 {
+		return []HistogramBucket{}, nil
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	err := CheckBuckets(interval, time.Unix(0, keys[0]), time.Unix(0, keys[len(keys)-1]))
+	if err != nil {
+		return nil, err
+	}
+
+	filled := []HistogramBucket{}
+	for key := keys[0]; key <= keys[len(keys)-1]; key += int64(interval) {
+		if bucket, ok := counts[key]; ok {
+			filled = append(filled, bucket)
+		} else {
+			filled = append(filled, HistogramBucket{Timestamp: time.Unix(0, key).UTC()})
+		}
+	}
+	return filled, nil
+}
+
+// CountHistogram counts entries matching the parameters like the date histograms
+// of the Elasticsearch provider: the interval is picked to cover the entries
+// when none is given, and a fixed interval covers the requested time range.
+// The options must have been validated.
+func CountHistogram(entries []LogEntry, params Parameters, options HistogramOptions) (Histogram, error) {
+	if len(entries) == 0 && len(options.Interval) == 0 {
+		return Histogram{Buckets: []HistogramBucket{}}, nil
+	}
+	var first, last time.Time
+	for i, entry := range entries {
+		if i == 0 {
+			first, last = entry.Timestamp, entry.Timestamp
+		}
+		if entry.Timestamp.Before(first) {
+			first = entry.Timestamp
+		}
+		if entry.Timestamp.After(last) {
+			last = entry.Timestamp
+		}
+	}
+
+	var from, to time.Time
+	interval := options.Interval
+	if len(interval) > 0 {
+		timeRange, _ := params.TimeRange(time.Now())
+		from, to = timeRange.Start, timeRange.Finish
+	} else {
+		interval = AutoInterval(first, last, options.Buckets)
+	}
+	width, _ := ParseInterval(interval)
+	buckets, err := CountBuckets(entries, width, options.SplitBy, from, to)
+	if err != nil {
+		return Histogram{}, err
+	}
+	return Histogram{Interval: interval, Buckets: buckets}, nil
+}
@@ -0,0 +1,399 @@
+// Package logstest checks that LogsProvider implementations answer like the
+// Elasticsearch provider.
+package logstest
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+)
+
+const (
+	oauthNamespace  = "openshift-oauth-apiserver"
+	etcdNamespace   = "openshift-etcd"
+	etcdPod         = "etcd-ip-10-0-162-9.ec2.internal"
+	apiserverPod    = "kube-apiserver-ip-10-0-162-9.ec2.internal"
+	apiserverSyncer = "kube-apiserver-cert-syncer"
+)
+
+type conformanceCase struct {
+	name     string
+	query    func(provider logs.LogsProvider) (logs.Page, error)
+	count    int
+	nextPage bool
+	err      error
+}
+
+func filterLogs(params logs.Parameters) func(logs.LogsProvider) (logs.Page, error) {
+	return func(provider logs.LogsProvider) (logs.Page, error) {
+		return provider.FilterLogs(context.Background(), params)
+	}
+}
+
+// search runs a search with a filter tree given in JSON.
+func search(params logs.Parameters, filter string) func(logs.LogsProvider) (logs.Page, error) {
+	return func(provider logs.LogsProvider) (logs.Page, error) {
+		var condition logs.Condition
+		err := json.Unmarshal([]byte(filter), &condition)
+		if err != nil {
+			return logs.Page{}, err
+		}
+		return provider.Search(context.Background(), params, condition)
+	}
+}
+
+var conformanceCases = []conformanceCase{
+	{name: "Default page size", query: filterLogs(logs.Parameters{}), count: 1000, nextPage: true},
+	{name: "Namespace", query: filterLogs(logs.Parameters{Namespace: oauthNamespace}), count: 18},
+	{name: "Page size", query: filterLogs(logs.Parameters{Namespace: oauthNamespace, MaxLogs: "5"}), count: 5, nextPage: true},
+	{name: "Page size matching every log", query: filterLogs(logs.Parameters{Namespace: oauthNamespace, MaxLogs: "18"}), count: 18},
+	{name: "Level", query: filterLogs(logs.Parameters{Namespace: etcdNamespace, Level: "unknown"}), count: 3},
+	{name: "Other level", query: filterLogs(logs.Parameters{Namespace: etcdNamespace, Level: "info"}), count: 0},
+	{name: "Index alias", query: filterLogs(logs.Parameters{Index: "infra", Podname: etcdPod}), count: 3},
+	{name: "Empty index", query: filterLogs(logs.Parameters{Index: "app"}), count: 0},
+	{name: "Visible indices", query: filterLogs(logs.Parameters{Indices: []string{"infra"}, Podname: etcdPod}), count: 3},
+	{name: "Hidden indices", query: filterLogs(logs.Parameters{Indices: []string{"app", "audit"}}), count: 0},
+	{name: "Hidden index", query: filterLogs(logs.Parameters{Index: "infra", Indices: []string{"app"}, Podname: etcdPod}), count: 0},
+	{name: "Container", query: filterLogs(logs.Parameters{ContainerName: apiserverSyncer}), count: 4},
+	{name: "Inclusive time range", query: filterLogs(logs.Parameters{Namespace: etcdNamespace,
+		StartTime: "2021-03-18T06:41:19.824314Z", FinishTime: "2021-03-18T06:41:19.824314Z"}), count: 1},
+	{name: "Time range", query: filterLogs(logs.Parameters{Namespace: etcdNamespace,
+		StartTime: "2021-03-18T06:41:19.824314Z", FinishTime: "2021-03-18T06:41:21.249829Z"}), count: 3},
+	{name: "Start time only", query: filterLogs(logs.Parameters{Namespace: etcdNamespace, StartTime: "2021-03-18T06:41:21.249829Z"}), count: 1},
+	{name: "Finish time only", query: filterLogs(logs.Parameters{Namespace: etcdNamespace, FinishTime: "2021-03-18T06:41:19.824314Z"}), count: 1},
+	{name: "Epoch milliseconds", query: filterLogs(logs.Parameters{Namespace: etcdNamespace,
+		StartTime: "1616049679824", FinishTime: "1616049681250"}), count: 3},
+	{name: "Date math", query: filterLogs(logs.Parameters{Namespace: etcdNamespace, StartTime: "2021-03-18T06:41:20Z", FinishTime: "now-1d/d"}), count: 2},
+	{name: "Since", query: filterLogs(logs.Parameters{Namespace: etcdNamespace, Since: "2h"}), count: 0},
+	{name: "Message word", query: filterLogs(logs.Parameters{Namespace: etcdNamespace, Query: "health"}), count: 2},
+	{name: "Excluded message word", query: filterLogs(logs.Parameters{Namespace: etcdNamespace, Query: "-health"}), count: 1},
+	{name: "Message phrase", query: filterLogs(logs.Parameters{Namespace: etcdNamespace, Query: `"rejected connection"`}), count: 1},
+	{name: "Visible namespaces", query: filterLogs(logs.Parameters{Namespaces: []string{etcdNamespace, "openshift-monitoring"}}), count: 6},
+	{name: "No visible namespace", query: filterLogs(logs.Parameters{Namespaces: []string{}}), count: 0},
+	{name: "Namespaces", query: filterLogs(logs.Parameters{Namespace: etcdNamespace + "," + oauthNamespace}), count: 21},
+	{name: "Namespace pattern", query: filterLogs(logs.Parameters{Namespace: "openshift-kube-*"}), count: 43},
+	{name: "Excluded namespace pattern", query: filterLogs(logs.Parameters{Namespace: "openshift-*", ExcludeNamespace: "openshift-kube-*"}), count: 30},
+	{name: "Excluded namespace keeps logs without namespace", query: filterLogs(logs.Parameters{ExcludeNamespace: "openshift-kube-*"}), count: 992},
+	{name: "Pod pattern", query: filterLogs(logs.Parameters{Podname: "etcd-ip-10-0-162-9.ec2.interna?"}), count: 3},
+	{name: "Levels", query: filterLogs(logs.Parameters{Namespace: etcdNamespace, Level: "info,unknown"}), count: 3},
+	{name: "Excluded level", query: filterLogs(logs.Parameters{ExcludeLevel: "unknown"}), count: 962},
+	{name: "Namespace logs excluding a container", query: func(provider logs.LogsProvider) (logs.Page, error) {
+		return provider.FilterNamespaceLogs(context.Background(), logs.Parameters{Namespace: "openshift-kube-apiserver", ExcludeContainerName: "*-cert-syncer"})
+	}, count: 12},
+	{name: "Namespace logs", query: func(provider logs.LogsProvider) (logs.Page, error) {
+		return provider.FilterNamespaceLogs(context.Background(), logs.Parameters{Namespace: "openshift-kube-apiserver", Podname: "ignored"})
+	}, count: 16},
+	{name: "Pod logs", query: func(provider logs.LogsProvider) (logs.Page, error) {
+		return provider.FilterPodLogs(context.Background(), logs.Parameters{Namespace: "openshift-kube-apiserver", Podname: apiserverPod})
+	}, count: 14},
+	{name: "Container logs", query: func(provider logs.LogsProvider) (logs.Page, error) {
+		return provider.FilterContainerLogs(context.Background(), logs.Parameters{Namespace: "openshift-kube-apiserver", Podname: apiserverPod, ContainerName: apiserverSyncer})
+	}, count: 2},
+	{name: "Label logs", query: func(provider logs.LogsProvider) (logs.Page, error) {
+		return provider.FilterLabelLogs(context.Background(), logs.Parameters{}, []string{"app=etcd", "revision=3"})
+	}, count: 3},
+	{name: "Label logs missing a label", query: func(provider logs.LogsProvider) (logs.Page, error) {
+		return provider.FilterLabelLogs(context.Background(), logs.Parameters{}, []string{"app=etcd", "revision=5"})
+	}, count: 0},
+	{name: "Entity logs", query: func(provider logs.LogsProvider) (logs.Page, error) {
+		return provider.FilterEntityLogs(context.Background(), logs.Parameters{Namespace: oauthNamespace}, "deployment", "apiserver")
+	}, count: 18},
+	{name: "Logs ignore the namespace", query: func(provider logs.LogsProvider) (logs.Page, error) {
+		return provider.Logs(context.Background(), logs.Parameters{Namespace: etcdNamespace, Level: "unknown"})
+	}, count: 73},
+	{name: "Search either namespace", query: search(logs.Parameters{},
+		`{"or": [{"field": "namespace", "eq": "`+etcdNamespace+`"}, {"field": "namespace", "eq": "`+oauthNamespace+`"}]}`), count: 21},
+	{name: "Search namespace prefix excluding containers", query: search(logs.Parameters{},
+		`{"and": [{"field": "namespace", "prefix": "openshift-kube-"}, {"not": {"field": "container", "in": ["`+apiserverSyncer+`", "kube-scheduler"]}}]}`), count: 39},
+	{name: "Search levels and message", query: search(logs.Parameters{},
+		`{"and": [{"field": "namespace", "eq": "`+etcdNamespace+`"}, {"field": "level", "in": ["info", "unknown"]}, {"field": "message", "match": "-health"}]}`), count: 1},
+	{name: "Search time range", query: search(logs.Parameters{},
+		`{"and": [{"field": "namespace", "eq": "`+etcdNamespace+`"}, {"field": "timestamp", "range": {"gte": "2021-03-18T06:41:19.824Z", "lt": "1616049681249"}}]}`), count: 2},
+	{name: "Search logs without namespace", query: search(logs.Parameters{}, `{"not": {"field": "namespace", "exists": true}}`), count: 962},
+	{name: "Search label", query: search(logs.Parameters{}, `{"field": "label", "eq": "app=etcd"}`), count: 3},
+	{name: "Search index alias", query: search(logs.Parameters{},
+		`{"and": [{"field": "index", "eq": "infra"}, {"field": "pod", "eq": "`+etcdPod+`"}]}`), count: 3},
+	{name: "Search along with filter parameters", query: search(logs.Parameters{Namespace: etcdNamespace}, `{"field": "level", "eq": "unknown"}`), count: 3},
+	{name: "Search hidden namespace", query: search(logs.Parameters{Namespaces: []string{etcdNamespace}}, `{"field": "namespace", "eq": "`+oauthNamespace+`"}`), count: 0},
+	{name: "Search unknown field", query: search(logs.Parameters{}, `{"and": [{"field": "level", "eq": "info"}, {"field": "user", "eq": "admin"}]}`),
+		err: logs.InvalidSearch("filter.and[1].field", `unknown field "user", one of container, hostname, index, label, level, message, namespace, pod, timestamp, viaq_msg_id is required`)},
+	{name: "Search operator not applying to the field", query: search(logs.Parameters{}, `{"field": "message", "eq": "timeout"}`),
+		err: logs.InvalidSearch("filter.eq", "eq does not apply to message, which takes match, exists")},
+	{name: "Search condition with two operators", query: search(logs.Parameters{}, `{"field": "level", "eq": "info", "in": ["unknown"]}`),
+		err: logs.InvalidSearch("filter", "exactly one of and, or, not, eq, in, prefix, range, exists or match is required")},
+	{name: "Search empty values", query: search(logs.Parameters{}, `{"not": {"field": "level", "in": ["info", ""]}}`),
+		err: logs.InvalidSearch("filter.not.in[1]", "a value is required")},
+	{name: "Invalid time", query: filterLogs(logs.Parameters{StartTime: "yesterday", FinishTime: "2021-03-18T06:41:21Z"}), err: logs.InvalidTimeStamp()},
+	{name: "Invalid date math", query: filterLogs(logs.Parameters{FinishTime: "now-1x"}), err: logs.InvalidTimeStamp()},
+	{name: "Invalid since", query: filterLogs(logs.Parameters{Since: "15"}), err: logs.InvalidTimeStamp()},
+	{name: "Start time after finish time", query: filterLogs(logs.Parameters{StartTime: "2021-03-18T06:41:21Z", FinishTime: "2021-03-18T06:41:20Z"}), err: logs.InvalidTimeRange()},
+	{name: "Since along with start time", query: filterLogs(logs.Parameters{StartTime: "2021-03-18T06:41:21Z", Since: "15m"}), err: logs.InvalidTimeRange()},
+	{name: "Invalid page size", query: filterLogs(logs.Parameters{MaxLogs: "-1"}), err: logs.InvalidLimit()},
+	{name: "Invalid order", query: filterLogs(logs.Parameters{Order: "random"}), err: logs.InvalidOrder()},
+	{name: "Invalid cursor", query: filterLogs(logs.Parameters{Cursor: "garbage"}), err: logs.InvalidCursor()},
+	{name: "Invalid message query", query: filterLogs(logs.Parameters{Query: `"unclosed`}), err: logs.InvalidQuery()},
+	{name: "Invalid filter", query: filterLogs(logs.Parameters{Namespace: etcdNamespace + ",,"}), err: logs.InvalidFilter()},
+	{name: "Invalid entity", query: func(provider logs.LogsProvider) (logs.Page, error) {
+		return provider.FilterEntityLogs(context.Background(), logs.Parameters{Namespace: oauthNamespace}, "replicaset", "apiserver")
+	}, err: logs.InvalidEntity()},
+}
+
+// TestProvider runs the conformance suite against a provider serving exactly
+// the logs of test-logs-mapping.json, the dump the e2e tests load into Elasticsearch.
+func TestProvider(t *testing.T, provider logs.LogsProvider) {
+	for _, tt := range conformanceCases {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := tt.query(provider)
+			if !sameError(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if len(page.Logs) != tt.count {
+				t.Errorf("expected %d logs, got %d", tt.count, len(page.Logs))
+			}
+			if (len(page.NextCursor) > 0) != tt.nextPage {
+				t.Errorf("expected a next page: %v, got cursor %q", tt.nextPage, page.NextCursor)
+			}
+		})
+	}
+	t.Run("Pagination", func(t *testing.T) {
+		testPagination(t, provider)
+	})
+	t.Run("Histogram", func(t *testing.T) {
+		histogram, err := provider.Histogram(context.Background(), logs.Parameters{Namespace: oauthNamespace}, logs.HistogramOptions{Interval: "1m", SplitBy: "level"})
+		if err != nil {
+			t.Fatalf("unexpected error. E: %v", err)
+		}
+		expected := logs.Histogram{Interval: "1m", Buckets: []logs.HistogramBucket{{
+			Timestamp: time.Date(2021, 3, 18, 6, 41, 0, 0, time.UTC),
+			Count:     18,
+			Split:     map[string]int64{"unknown": 18},
+		}}}
+		if !reflect.DeepEqual(histogram, expected) {
+			t.Errorf("expected %+v, got %+v", expected, histogram)
+		}
+		params := logs.Parameters{StartTime: "2021-03-18T06:00:00Z", FinishTime: "2021-03-18T07:00:00Z"}
+		_, err = provider.Histogram(context.Background(), params, logs.HistogramOptions{Interval: "1ms"})
+		if !sameError(err, logs.InvalidHistogram()) {
+			t.Errorf("expected error %v for too many buckets, got %v", logs.InvalidHistogram(), err)
+		}
+		params = logs.Parameters{Namespace: oauthNamespace, StartTime: "2021-03-18T06:00:00Z", FinishTime: "2021-03-18T07:00:00Z"}
+		histogram, err = provider.Histogram(context.Background(), params, logs.HistogramOptions{Interval: "1s"})
+		if err != nil || len(histogram.Buckets) != 3601 {
+			t.Errorf("expected 3601 buckets, got %d, %v", len(histogram.Buckets), err)
+		}
+	})
+	t.Run("Values", func(t *testing.T) {
+		values, err := provider.Values(context.Background(), logs.Parameters{}, "namespace", logs.ValuesOptions{Prefix: "openshift-kube", Size: 2})
+		if err != nil {
+			t.Fatalf("unexpected error. E: %v", err)
+		}
+		expected := []logs.FieldValue{{Value: "openshift-kube-apiserver", Count: 16}, {Value: "openshift-kube-scheduler", Count: 16}}
+		if !reflect.DeepEqual(values.Values, expected) {
+			t.Errorf("expected %v, got %v", expected, values.Values)
+		}
+		values, err = provider.Values(context.Background(), logs.Parameters{Namespace: etcdNamespace}, "label", logs.ValuesOptions{Prefix: "app=", Size: 10})
+		expected = []logs.FieldValue{{Value: "app=etcd", Count: 3}}
+		if err != nil || !reflect.DeepEqual(values.Values, expected) {
+			t.Errorf("expected %v, got %v, %v", expected, values.Values, err)
+		}
+	})
+	t.Run("Context", func(t *testing.T) {
+		testContext(t, provider)
+	})
+	t.Run("Projection", func(t *testing.T) {
+		testProjection(t, provider)
+	})
+	t.Run("Export", func(t *testing.T) {
+		count := 0
+		err := provider.Export(context.Background(), logs.Parameters{Namespace: oauthNamespace}, func(batch []logs.LogEntry) error {
+			count += len(batch)
+			return nil
+		})
+		if err != nil || count != 18 {
+			t.Errorf("expected 18 logs, got %d, %v", count, err)
+		}
+		count = 0
+		err = provider.Export(context.Background(), logs.Parameters{Namespace: "missing"}, func(batch []logs.LogEntry) error {
+			count += len(batch)
+			return nil
+		})
+		if err != nil || count != 0 {
+			t.Errorf("expected no logs and no error, got %d, %v", count, err)
+		}
+	})
+}
+
+// testPagination follows the cursors through the logs of a namespace in both
+// orders. Elasticsearch keeps timestamps to the millisecond, so the order is
+// checked at that precision.
+func testPagination(t *testing.T, provider logs.LogsProvider) {
+	for _, order := range []string{"desc", "asc"} {
+		params := logs.Parameters{Namespace: oauthNamespace, MaxLogs: "5", Order: order}
+		seen := map[string]bool{}
+		var previous time.Time
+		for pages := 1; ; pages++ {
+			page, err := provider.FilterLogs(context.Background(), params)
+			if err != nil {
+				t.Fatalf("unexpected error. E: %v", err)
+			}
+			for _, entry := range page.Logs {
+				if seen[entry.ID] {
+					t.Errorf("%s is returned twice in %s order", entry.ID, order)
+				}
+				seen[entry.ID] = true
+				timestamp := entry.Timestamp.Truncate(time.Millisecond)
+				if !previous.IsZero() && !timestamp.Equal(previous) && timestamp.After(previous) != (order == "asc") {
+					t.Errorf("%s is out of %s order", entry.ID, order)
+				}
+				previous = timestamp
+			}
+			if len(page.NextCursor) == 0 {
+				if pages != 4 {
+					t.Errorf("expected 4 pages in %s order, got %d", order, pages)
+				}
+				break
+			}
+			params.Cursor = page.NextCursor
+		}
+		if len(seen) != 18 {
+			t.Errorf("expected 18 logs in %s order, got %d", order, len(seen))
+		}
+	}
+}
+
+// testProjection checks that only the requested fields of the source are
+// returned, along with the fields of the entries taken from them.
+func testProjection(t *testing.T, provider logs.LogsProvider) {
+	page, err := provider.FilterLogs(context.Background(), logs.Parameters{Namespace: etcdNamespace, Fields: "compact"})
+	if err != nil || len(page.Logs) != 3 {
+		t.Fatalf("expected 3 logs, got %d, %v", len(page.Logs), err)
+	}
+	for _, entry := range page.Logs {
+		var source map[string]interface{}
+		_ = json.Unmarshal(entry.Source, &source)
+		if keys := sortedKeys(source); !reflect.DeepEqual(keys, []string{"@timestamp", "kubernetes", "level", "message"}) {
+			t.Errorf("expected the compact fields, got %v", keys)
+		}
+		kubernetes, _ := source["kubernetes"].(map[string]interface{})
+		if keys := sortedKeys(kubernetes); !reflect.DeepEqual(keys, []string{"container_name", "namespace_name", "pod_name"}) {
+			t.Errorf("expected the identity of the container, got %v", keys)
+		}
+		if entry.KubernetesMetadata().NamespaceName != etcdNamespace || len(entry.Message) == 0 || len(entry.Hostname) > 0 || entry.Timestamp.IsZero() {
+			t.Errorf("expected the entry to keep the compact fields only, got %+v", entry)
+		}
+	}
+
+	count := 0
+	err = provider.Export(context.Background(), logs.Parameters{Namespace: oauthNamespace, ExcludeFields: "message,kubernetes.*labels"}, func(batch []logs.LogEntry) error {
+		for _, entry := range batch {
+			count++
+			var source map[string]interface{}
+			_ = json.Unmarshal(entry.Source, &source)
+			kubernetes, _ := source["kubernetes"].(map[string]interface{})
+			if _, ok := source["message"]; ok || len(entry.Message) > 0 || kubernetes["labels"] != nil || kubernetes["flat_labels"] != nil || kubernetes["pod_name"] == nil {
+				t.Errorf("expected the message and labels to be left out, got %s", entry.Source)
+			}
+		}
+		return nil
+	})
+	if err != nil || count != 18 {
+		t.Errorf("expected 18 logs, got %d, %v", count, err)
+	}
+
+	_, err = provider.FilterLogs(context.Background(), logs.Parameters{Fields: "message,"})
+	if !sameError(err, logs.InvalidFields()) {
+		t.Errorf("expected error %v, got %v", logs.InvalidFields(), err)
+	}
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// testContext fetches the context of a log whose neighbours have distinct
+// timestamps at the millisecond, so that every provider picks the same ones.
+func testContext(t *testing.T, provider logs.LogsProvider) {
+	const id = "MGJkYmRmZTItY2MwNC00OTZkLWI3MzAtMmE3MDc5NDY1ZWEz"
+	logContext, err := provider.Context(context.Background(), logs.Parameters{}, id, logs.ContextOptions{Size: 3})
+	if err != nil {
+		t.Fatalf("unexpected error. E: %v", err)
+	}
+	if logContext.Entry.ID != id {
+		t.Errorf("expected the entry %s, got %s", id, logContext.Entry.ID)
+	}
+	expectedBefore := []string{
+		"MDRmMjdiNGYtODhjYy00YTIzLTkwNGMtMGJjMjhiMmUxM2Q2",
+		"NmQ2ZmY2N2MtZjYwZi00M2E4LWJkMTMtNTU2MDBmZDRjZWEz",
+		"OWE1ZjE2ZjMtN2NjMi00N2MzLThiOWQtMDYzZDZkYzM4YzMx",
+	}
+	expectedAfter := []string{
+		"MzIxZjQ0OGUtZGEwZC00ZGE1LWJmNjItOTgxODM0MDE3ODIx",
+		"OWI5ZGE0NDctNjc0Mi00NWJkLWI2ZTUtNDE5MDUwOTcyYTEy",
+	}
+	if before := sortedIDs(logContext.Before); !reflect.DeepEqual(before, expectedBefore) {
+		t.Errorf("expected the logs before to be %v, got %v", expectedBefore, before)
+	}
+	if after := sortedIDs(logContext.After); !reflect.DeepEqual(after, expectedAfter) {
+		t.Errorf("expected the logs after to be %v, got %v", expectedAfter, after)
+	}
+	previous := time.Time{}
+	for _, entry := range append(append(logContext.Before, logContext.Entry), logContext.After...) {
+		timestamp := entry.Timestamp.Truncate(time.Millisecond)
+		if timestamp.Before(previous) {
+			t.Errorf("%s is out of order", entry.ID)
+		}
+		previous = timestamp
+	}
+
+	logContext, err = provider.Context(context.Background(), logs.Parameters{}, id, logs.ContextOptions{Size: 1})
+	if err != nil || len(logContext.Before) != 1 || len(logContext.After) != 1 {
+		t.Errorf("expected a log before and after, got %d and %d, %v", len(logContext.Before), len(logContext.After), err)
+	}
+	nodeLog := "OGQxYTQyZmUtMGZkYy00YWE5LTk0M2UtZmUzYTkyMzAxNWJi"
+	logContext, err = provider.Context(context.Background(), logs.Parameters{}, nodeLog, logs.ContextOptions{})
+	if err != nil || logContext.Entry.ID != nodeLog || len(logContext.Before) != 0 || len(logContext.After) != 0 {
+		t.Errorf("expected a node log alone, got %+v, %v", logContext, err)
+	}
+	_, err = provider.Context(context.Background(), logs.Parameters{Namespaces: []string{etcdNamespace}}, id, logs.ContextOptions{})
+	if !sameError(err, logs.NotFoundError()) {
+		t.Errorf("expected a log of a hidden namespace not to be found, got %v", err)
+	}
+	_, err = provider.Context(context.Background(), logs.Parameters{Namespace: etcdNamespace}, id, logs.ContextOptions{})
+	if !sameError(err, logs.NotFoundError()) {
+		t.Errorf("expected a log of another namespace not to be found, got %v", err)
+	}
+	_, err = provider.Context(context.Background(), logs.Parameters{}, "missing", logs.ContextOptions{})
+	if !sameError(err, logs.NotFoundError()) {
+		t.Errorf("expected error %v, got %v", logs.NotFoundError(), err)
+	}
+	_, err = provider.Context(context.Background(), logs.Parameters{}, id, logs.ContextOptions{Size: -1})
+	if !sameError(err, logs.InvalidContextSize()) {
+		t.Errorf("expected error %v, got %v", logs.InvalidContextSize(), err)
+	}
+}
+
+func sortedIDs(entries []logs.LogEntry) []string {
+	ids := []string{}
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func sameError(err error, expected error) bool {
+	if err == nil || expected == nil {
+		return err == expected
+	}
+	return err.Error() == expected.Error()
+}
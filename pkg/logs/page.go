@@ -0,0 +1,29 @@
+package logs
+
+import "strconv"
+
+// Page is a single page of logs returned by a LogsProvider. EndCursor points
+// right after the last entry of the page and is empty only when the page has
+// no entries. NextCursor is set to the same value when further entries match
+// the query.
+type Page struct {
+	Logs       []LogEntry
+	EndCursor  string
+	NextCursor string
+}
+
+// DefaultMaxLogs is the page size when "maxlogs" is not given and MaxLogsLimit
+// the largest page size accepted. Both can be changed in the configuration.
+var (
+	DefaultMaxLogs = 1000
+	MaxLogsLimit   = 1000
+)
+
+// PageSize returns the page size requested by valid parameters.
+func (params Parameters) PageSize() int {
+	if len(params.MaxLogs) == 0 {
+		return DefaultMaxLogs
+	}
+	maxLogs, _ := strconv.Atoi(params.MaxLogs)
+	return maxLogs
+}
@@ -1,13 +1,29 @@
 package logs
 
+// Parameters filter the logs. Namespace, Podname, ContainerName and Level take
+// comma separated values, which may contain the * and ? wildcards, and the
+// values of their Exclude counterparts are filtered out. StartTime and
+// FinishTime each bound the time range on their own, see TimeRange.
 type Parameters struct {
-	Namespace     string `form:"namespace"`
-	Index         string `form:"index"`
-	Podname       string `form:"podname"`
-	StartTime     string `form:"starttime"`
-	FinishTime    string `form:"finishtime"`
-	Level         string `form:"level"`
-	MaxLogs       string `form:"maxlogs"`
-	ContainerName string `form:"containername"`
-	Token         map[string]string
+	Namespace            string   `form:"namespace"`
+	Index                string   `form:"index"`
+	Podname              string   `form:"podname"`
+	StartTime            string   `form:"starttime"`
+	FinishTime           string   `form:"finishtime"`
+	Since                string   `form:"since"` // duration such as 15m, starting the time range that long ago
+	Level                string   `form:"level"`
+	MaxLogs              string   `form:"maxlogs"`
+	ContainerName        string   `form:"containername"`
+	ExcludeNamespace     string   `form:"exclude_namespace"`
+	ExcludePodname       string   `form:"exclude_podname"`
+	ExcludeContainerName string   `form:"exclude_containername"`
+	ExcludeLevel         string   `form:"exclude_level"`
+	Cursor               string   `form:"cursor"`
+	Query                string   `form:"q"`
+	Order                string   `form:"order"`          // "asc" for oldest first, newest first otherwise
+	Fields               string   `form:"fields"`         // comma separated fields of the source to return, see Projection
+	ExcludeFields        string   `form:"exclude_fields"` // comma separated fields of the source to leave out
+	Namespaces           []string `form:"-"`              // when not nil, only logs of these namespaces are visible to the caller
+	Indices              []string `form:"-"`              // when not nil, only logs of these indices (app, infra or audit) are visible to the caller
+	Token                map[string]string
 }
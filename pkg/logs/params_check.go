@@ -0,0 +1,48 @@
+package logs
+
+import (
+	"strconv"
+	"time"
+)
+
+// Validate checks the filter parameters shared by every provider.
+func (params Parameters) Validate() error {
+
+	_, err := params.TimeRange(time.Now())
+	if err != nil {
+		return err
+	}
+	if len(params.MaxLogs) > 0 {
+		maxLogs, err := strconv.Atoi(params.MaxLogs)
+		if err != nil || maxLogs < 0 || maxLogs > MaxLogsLimit {
+			return InvalidLimit()
+		}
+	}
+	if len(params.Query) > 0 {
+		_, err = ParseMessageQuery(params.Query)
+		if err != nil {
+			return err
+		}
+	}
+	for _, filter := range params.FieldFilters() {
+		err = filter.validate()
+		if err != nil {
+			return err
+		}
+	}
+	err = params.Projection().validate()
+	if err != nil {
+		return err
+	}
+	if params.Order != "" && params.Order != "asc" && params.Order != "desc" {
+		return InvalidOrder()
+	}
+	if len(params.Cursor) > 0 {
+		_, err = ParseCursor(params.Cursor)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package logs
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ProblemContentType is the media type of the error responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem describes an error to the client as RFC 7807 problem details, with
+// the machine-readable code of the error as an extension member.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+}
+
+// NewProblem describes err. Errors other than Error are internal errors,
+// whose message is not shown to the client.
+func NewProblem(err error) Problem {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Code: "internal_error", Message: "an internal server error occurred"}
+	}
+	status := e.Kind.Status()
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: e.Message,
+		Code:   e.Code,
+	}
+}
@@ -0,0 +1,156 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// CompactPreset is the value of the fields parameter keeping CompactFields.
+const CompactPreset = "compact"
+
+// CompactFields are the fields of the compact preset: the timestamp, level,
+// message and the identity of the container.
+var CompactFields = []string{"@timestamp", "level", "message", "kubernetes.namespace_name", "kubernetes.pod_name", "kubernetes.container_name"}
+
+// Projection selects the fields of the source of the logs to return, like
+// the includes and excludes of Elasticsearch source filtering. Fields are
+// paths such as kubernetes.pod_name, which may contain the * wildcard, and
+// an object is kept along with all of its fields. Without includes, every
+// field that is not excluded is kept.
+type Projection struct {
+	Includes []string
+	Excludes []string
+
+	includes *patterns
+	excludes *patterns
+}
+
+// Projection returns the projection of the fields and exclude_fields
+// parameters, the compact preset standing for CompactFields.
+func (params Parameters) Projection() Projection {
+	var projection Projection
+	for _, field := range SplitValues(params.Fields) {
+		if field == CompactPreset {
+			projection.Includes = append(projection.Includes, CompactFields...)
+		} else {
+			projection.Includes = append(projection.Includes, field)
+		}
+	}
+	projection.Excludes = SplitValues(params.ExcludeFields)
+	return projection.compiled()
+}
+
+// compiled returns the projection with its fields compiled for matching.
+func (projection Projection) compiled() Projection {
+	if projection.includes == nil || projection.excludes == nil {
+		projection.includes, projection.excludes = compilePatterns(projection.Includes), compilePatterns(projection.Excludes)
+	}
+	return projection
+}
+
+// Empty reports whether the projection keeps the whole source.
+func (projection Projection) Empty() bool {
+	return len(projection.Includes) == 0 && len(projection.Excludes) == 0
+}
+
+// validate checks that no field is empty, as in "a,,b".
+func (projection Projection) validate() error {
+	for _, field := range append(append([]string{}, projection.Includes...), projection.Excludes...) {
+		if len(strings.Trim(field, ".")) == 0 {
+			return InvalidFields()
+		}
+	}
+	return nil
+}
+
+// Source returns the fields of a JSON source kept by the projection.
+func (projection Projection) Source(source []byte) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(source))
+	decoder.UseNumber()
+	var document map[string]interface{}
+	err := decoder.Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+	kept, _ := projection.compiled().filter(document, "", len(projection.Includes) == 0)
+	if kept == nil {
+		kept = map[string]interface{}{}
+	}
+	return json.Marshal(kept)
+}
+
+// filter returns the part of the value at path that is kept, and whether any
+// is. included tells whether an enclosing object is included.
+func (projection Projection) filter(value interface{}, path string, included bool) (interface{}, bool) {
+	if len(path) > 0 {
+		if projection.excludes.matches(path) {
+			return nil, false
+		}
+		included = included || projection.includes.matches(path)
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		kept := map[string]interface{}{}
+		for key, field := range value {
+			fieldPath := key
+			if len(path) > 0 {
+				fieldPath = path + "." + key
+			}
+			if v, ok := projection.filter(field, fieldPath, included); ok {
+				kept[key] = v
+			}
+		}
+		return kept, included || len(kept) > 0
+	case []interface{}:
+		kept := []interface{}{}
+		for _, item := range value {
+			if v, ok := projection.filter(item, path, included); ok {
+				kept = append(kept, v)
+			}
+		}
+		return kept, included || len(kept) > 0
+	}
+	return value, included
+}
+
+// ProjectEntry keeps the fields of the source of the entry selected by the
+// projection, along with the fields of the entry taken from them, like the
+// Elasticsearch provider does with source filtering. The id, index and
+// timestamp, which identify the entry, are kept. Entries without a JSON
+// source are returned unchanged.
+func ProjectEntry(entry LogEntry, projection Projection) LogEntry {
+	if projection.Empty() || entry.Source == nil {
+		return entry
+	}
+	source, err := projection.Source(entry.Source)
+	if err != nil {
+		return entry
+	}
+	projected, err := NewLogEntry(entry.ID, entry.Index, source)
+	if err != nil {
+		return entry
+	}
+	projected.Timestamp = entry.Timestamp
+	projected.Hit = string(source)
+	var hit map[string]json.RawMessage
+	if json.Unmarshal([]byte(entry.Hit), &hit) == nil && hit["_source"] != nil {
+		hit["_source"] = source
+		data, _ := json.Marshal(hit)
+		projected.Hit = string(data)
+	}
+	return projected
+}
+
+// ProjectEntries applies ProjectEntry to every entry.
+func ProjectEntries(entries []LogEntry, projection Projection) []LogEntry {
+	if projection.Empty() {
+		return entries
+	}
+	projection = projection.compiled()
+	projected := make([]LogEntry, 0, len(entries))
+	for _, entry := range entries {
+		projected = append(projected, ProjectEntry(entry, projection))
+	}
+	return projected
+}
@@ -1,11 +1,33 @@
 package logs
 
+import "context"
+
+// ExportBatchSize is the number of logs handed over at once by Export.
+const ExportBatchSize = 1000
+
+// LogsProvider reads logs from a log store. The context of every method is the
+// one of the request being served: the queries are abandoned when it is done.
 type LogsProvider interface {
-	FilterLogs(params Parameters) ([]string, error)
-	FilterContainerLogs(params Parameters) ([]string, error)
-	FilterLabelLogs(params Parameters, labelList []string) ([]string, error)
-	FilterNamespaceLogs(params Parameters) ([]string, error)
-	FilterPodLogs(params Parameters) ([]string, error)
-	Logs(params Parameters) ([]string, error)
-	CheckReadiness() bool
+	FilterLogs(ctx context.Context, params Parameters) (Page, error)
+	FilterContainerLogs(ctx context.Context, params Parameters) (Page, error)
+	FilterEntityLogs(ctx context.Context, params Parameters, kind string, name string) (Page, error)
+	FilterLabelLogs(ctx context.Context, params Parameters, labelList []string) (Page, error)
+	FilterNamespaceLogs(ctx context.Context, params Parameters) (Page, error)
+	FilterPodLogs(ctx context.Context, params Parameters) (Page, error)
+	Logs(ctx context.Context, params Parameters) (Page, error)
+	// Search returns the logs matching the filter tree along with the filter
+	// parameters of FilterLogs.
+	Search(ctx context.Context, params Parameters, filter Condition) (Page, error)
+	Histogram(ctx context.Context, params Parameters, options HistogramOptions) (Histogram, error)
+	Values(ctx context.Context, params Parameters, field string, options ValuesOptions) (FieldValues, error)
+	// Context returns the entry whose document id or viaq_msg_id is id, along
+	// with the entries its container wrote right before and after it. Only
+	// the namespace and index of params and the visible namespaces and
+	// indices apply.
+	Context(ctx context.Context, params Parameters, id string, options ContextOptions) (LogContext, error)
+	// Export calls handle with every log matching the filter parameters of
+	// FilterLogs, one batch at a time, until there are none left, handle
+	// returns an error or ctx is done. Exporting no logs is not an error.
+	Export(ctx context.Context, params Parameters, handle func([]LogEntry) error) error
+	CheckReadiness(ctx context.Context) bool
 }
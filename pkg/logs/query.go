@@ -0,0 +1,59 @@
+package logs
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MessageTerm is a single condition of a full-text query on the log message.
+type MessageTerm struct {
+	Text   string
+	Phrase bool // the text was quoted and must appear as a whole
+	Negate bool // the text was prefixed with "-" and must not appear
+}
+
+// ParseMessageQuery splits a full-text query such as `timeout "connection refused" -probe`
+// into its terms. Words must all appear in the message, quoted phrases must appear
+// verbatim and terms prefixed with "-" must not appear.
+func ParseMessageQuery(query string) ([]MessageTerm, error) {
+	var terms []MessageTerm
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		term := MessageTerm{}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			term.Negate = true
+			i++
+		}
+		if runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, InvalidQuery()
+			}
+			term.Text = strings.TrimSpace(string(runes[i+1 : end]))
+			term.Phrase = true
+			i = end + 1
+		} else {
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			term.Text = string(runes[start:i])
+		}
+		if len(term.Text) > 0 {
+			terms = append(terms, term)
+		}
+	}
+	return terms, nil
+}
+
+// Matches reports whether the message satisfies the term, ignoring case.
+func (term MessageTerm) Matches(message string) bool {
+	return strings.Contains(strings.ToLower(message), strings.ToLower(term.Text)) != term.Negate
+}
@@ -0,0 +1,298 @@
+package logs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxSearchDepth is how deeply the conditions of a search filter may nest.
+const MaxSearchDepth = 10
+
+// SearchRequest is the body of a search: a filter tree applied on top of the
+// filter parameters.
+type SearchRequest struct {
+	Filter *Condition `json:"filter"`
+}
+
+// Condition is a node of a search filter. It either combines other conditions
+// with and, or or not, or tests a field with one operator, such as
+// {"field": "level", "in": ["error", "warning"]}.
+type Condition struct {
+	And    []Condition `json:"and,omitempty"`
+	Or     []Condition `json:"or,omitempty"`
+	Not    *Condition  `json:"not,omitempty"`
+	Field  string      `json:"field,omitempty"`
+	Eq     *string     `json:"eq,omitempty"`     // the field has the value
+	In     []string    `json:"in,omitempty"`     // the field has one of the values
+	Prefix *string     `json:"prefix,omitempty"` // the field starts with the value
+	Range  *TimeBounds `json:"range,omitempty"`  // the timestamp is within the bounds
+	Exists *bool       `json:"exists,omitempty"` // the field has a value, or has none when false
+	Match  *string     `json:"match,omitempty"`  // the message matches a message query, like q
+}
+
+// TimeBounds are the bounds of a range condition, which take the same times
+// as starttime and finishtime.
+type TimeBounds struct {
+	Gt  string `json:"gt,omitempty"`
+	Gte string `json:"gte,omitempty"`
+	Lt  string `json:"lt,omitempty"`
+	Lte string `json:"lte,omitempty"`
+}
+
+// SearchFields are the ViaQ fields a search filter can test, with the
+// operators each of them accepts. index matches the alias of the rollover
+// indices, like the index parameter, and label the flat labels, such as app=etcd.
+var SearchFields = map[string][]string{
+	"namespace":   {"eq", "in", "prefix", "exists"},
+	"pod":         {"eq", "in", "prefix", "exists"},
+	"container":   {"eq", "in", "prefix", "exists"},
+	"label":       {"eq", "in", "prefix", "exists"},
+	"level":       {"eq", "in", "prefix", "exists"},
+	"hostname":    {"eq", "in", "prefix", "exists"},
+	"viaq_msg_id": {"eq", "in", "exists"},
+	"index":       {"eq", "in", "prefix"},
+	"message":     {"match", "exists"},
+	"timestamp":   {"range"},
+}
+
+// Operator returns the operator of the condition, such as and or eq, or an
+// empty string unless exactly one is given.
+func (condition Condition) Operator() string {
+	var operators []string
+	for name, set := range map[string]bool{
+		"and":    condition.And != nil,
+		"or":     condition.Or != nil,
+		"not":    condition.Not != nil,
+		"eq":     condition.Eq != nil,
+		"in":     condition.In != nil,
+		"prefix": condition.Prefix != nil,
+		"range":  condition.Range != nil,
+		"exists": condition.Exists != nil,
+		"match":  condition.Match != nil,
+	} {
+		if set {
+			operators = append(operators, name)
+		}
+	}
+	if len(operators) != 1 {
+		return ""
+	}
+	return operators[0]
+}
+
+// Validate checks the filter, reporting the path of the first invalid
+// condition, such as filter.and[1].field.
+func (condition Condition) Validate() error {
+	return condition.validate("filter", 1)
+}
+
+func (condition Condition) validate(path string, depth int) error {
+	if depth > MaxSearchDepth {
+		return InvalidSearch(path, fmt.Sprintf("conditions cannot nest more than %d levels deep", MaxSearchDepth))
+	}
+	operator := condition.Operator()
+	switch operator {
+	case "":
+		return InvalidSearch(path, "exactly one of and, or, not, eq, in, prefix, range, exists or match is required")
+	case "and", "or", "not":
+		if len(condition.Field) > 0 {
+			return InvalidSearch(path+".field", fmt.Sprintf("%s does not test a field", operator))
+		}
+		if operator == "not" {
+			return condition.Not.validate(path+".not", depth+1)
+		}
+		children := condition.And
+		if operator == "or" {
+			children = condition.Or
+		}
+		if len(children) == 0 {
+			return InvalidSearch(path+"."+operator, "at least one condition is required")
+		}
+		for i, child := range children {
+			err := child.validate(fmt.Sprintf("%s.%s[%d]", path, operator, i), depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	operators, ok := SearchFields[condition.Field]
+	if !ok {
+		return InvalidSearch(path+".field", fmt.Sprintf("unknown field %q, one of %s is required", condition.Field, strings.Join(searchFieldNames(), ", ")))
+	}
+	if !containsString(operators, operator) {
+		return InvalidSearch(path+"."+operator, fmt.Sprintf("%s does not apply to %s, which takes %s", operator, condition.Field, strings.Join(operators, ", ")))
+	}
+	path += "." + operator
+	switch operator {
+	case "eq", "prefix":
+		value := condition.Eq
+		if operator == "prefix" {
+			value = condition.Prefix
+		}
+		if len(*value) == 0 {
+			return InvalidSearch(path, "a value is required")
+		}
+	case "in":
+		if len(condition.In) == 0 {
+			return InvalidSearch(path, "at least one value is required")
+		}
+		for i, value := range condition.In {
+			if len(value) == 0 {
+				return InvalidSearch(fmt.Sprintf("%s[%d]", path, i), "a value is required")
+			}
+		}
+	case "range":
+		return condition.Range.validate(path)
+	case "match":
+		if len(*condition.Match) == 0 {
+			return InvalidSearch(path, "a message query is required")
+		}
+		if _, err := ParseMessageQuery(*condition.Match); err != nil {
+			return InvalidSearch(path, "every quoted phrase must be closed")
+		}
+	}
+	return nil
+}
+
+func (bounds TimeBounds) validate(path string) error {
+	if len(bounds.Gt) > 0 && len(bounds.Gte) > 0 || len(bounds.Lt) > 0 && len(bounds.Lte) > 0 {
+		return InvalidSearch(path, "gt and gte, like lt and lte, cannot be given together")
+	}
+	times, err := bounds.Times(time.Now())
+	if err != nil {
+		return InvalidSearch(path, "the bounds take a time in the format YYYY-MM-DD'T'HH:mm:ss.SSS[TIMEZONE ex:'Z'], milliseconds since the epoch or date math such as now-1h/h")
+	}
+	if len(times) == 0 {
+		return InvalidSearch(path, "at least one of gt, gte, lt or lte is required")
+	}
+	return nil
+}
+
+// Times resolves the bounds against now, keyed by gt, gte, lt and lte. Like
+// a range query, date math is rounded up for gt and lte and down otherwise.
+func (bounds TimeBounds) Times(now time.Time) (map[string]time.Time, error) {
+	times := map[string]time.Time{}
+	for _, bound := range []struct {
+		name    string
+		value   string
+		roundUp bool
+	}{{"gt", bounds.Gt, true}, {"gte", bounds.Gte, false}, {"lt", bounds.Lt, false}, {"lte", bounds.Lte, true}} {
+		if len(bound.value) == 0 {
+			continue
+		}
+		t, err := ParseTime(bound.value, now, bound.roundUp)
+		if err != nil {
+			return nil, err
+		}
+		times[bound.name] = t
+	}
+	return times, nil
+}
+
+// Filter returns a Filter matching the entries satisfying the condition,
+// which must have been validated, resolving times against now.
+func (condition Condition) Filter(now time.Time) Filter {
+	switch condition.Operator() {
+	case "and", "or":
+		all := condition.Operator() == "and"
+		children := condition.And
+		if !all {
+			children = condition.Or
+		}
+		filters := make([]Filter, 0, len(children))
+		for _, child := range children {
+			filters = append(filters, child.Filter(now))
+		}
+		return func(entry LogEntry) bool {
+			for _, filter := range filters {
+				if filter(entry) != all {
+					return !all
+				}
+			}
+			return all
+		}
+	case "not":
+		filter := condition.Not.Filter(now)
+		return func(entry LogEntry) bool { return !filter(entry) }
+	case "eq":
+		return condition.valuesFilter(func(value string) bool { return sameValue(condition.Field, value, *condition.Eq) })
+	case "in":
+		return condition.valuesFilter(func(value string) bool {
+			for _, expected := range condition.In {
+				if sameValue(condition.Field, value, expected) {
+					return true
+				}
+			}
+			return false
+		})
+	case "prefix":
+		return condition.valuesFilter(func(value string) bool { return strings.HasPrefix(value, *condition.Prefix) })
+	case "exists":
+		exists := condition.valuesFilter(func(value string) bool { return true })
+		return func(entry LogEntry) bool { return exists(entry) == *condition.Exists }
+	case "range":
+		times, _ := condition.Range.Times(now)
+		return func(entry LogEntry) bool {
+			return (times["gt"].IsZero() || entry.Timestamp.After(times["gt"])) &&
+				(times["gte"].IsZero() || !entry.Timestamp.Before(times["gte"])) &&
+				(times["lt"].IsZero() || entry.Timestamp.Before(times["lt"])) &&
+				(times["lte"].IsZero() || !entry.Timestamp.After(times["lte"]))
+		}
+	case "match":
+		terms, _ := ParseMessageQuery(*condition.Match)
+		return func(entry LogEntry) bool {
+			for _, term := range terms {
+				if !term.Matches(entry.Message) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return func(entry LogEntry) bool { return false }
+}
+
+// valuesFilter matches the entries having a value of the field of the
+// condition that satisfies matches. Missing values never match.
+func (condition Condition) valuesFilter(matches func(value string) bool) Filter {
+	return func(entry LogEntry) bool {
+		for _, value := range entry.fieldValues(condition.Field) {
+			if len(value) > 0 && matches(value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// sameValue compares a value of a field to an expected one, an index being
+// the same as the alias of its rollover indices.
+func sameValue(field string, value string, expected string) bool {
+	if field == "index" {
+		return InIndex(value, expected)
+	}
+	return value == expected
+}
+
+// SearchFilter matches the logs satisfying the condition along with the
+// filter parameters of FilterLogs.
+func SearchFilter(params Parameters, condition Condition) Filter {
+	shared := params.Filter()
+	filter := condition.Filter(time.Now())
+	return func(entry LogEntry) bool {
+		return shared(entry) && filter(entry)
+	}
+}
+
+func searchFieldNames() []string {
+	names := make([]string, 0, len(SearchFields))
+	for name := range SearchFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
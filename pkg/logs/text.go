@@ -0,0 +1,82 @@
+package logs
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTextTemplate prints entries like `oc logs --prefix --timestamps` does.
+const DefaultTextTemplate = `{{.Timestamp}} {{.Namespace}}/{{.Pod}}/{{.Container}} {{.Level}} {{.Message}}`
+
+// TextFormatter prints log entries as lines of plain text.
+type TextFormatter struct {
+	template *template.Template
+}
+
+// TextLine holds the fields available to the text template. Entry gives
+// access to anything else, such as {{.Entry.Kubernetes.Labels}}.
+type TextLine struct {
+	Timestamp string
+	Namespace string
+	Pod       string
+	Container string
+	Level     string
+	Message   string
+	Index     string
+	Hostname  string
+	ID        string
+	Entry     LogEntry
+}
+
+// NewTextFormatter parses a Go template executed once per log entry. The
+// template is tried on an empty entry so that unknown fields are reported
+// right away rather than in the middle of a response.
+func NewTextFormatter(text string) (*TextFormatter, error) {
+	tmpl, err := template.New("text").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	formatter := &TextFormatter{template: tmpl}
+	err = formatter.Format(ioutil.Discard, LogEntry{Kubernetes: &KubernetesMetadata{}})
+	if err != nil {
+		return nil, err
+	}
+	return formatter, nil
+}
+
+// MustTextFormatter is like NewTextFormatter but panics when the template is
+// invalid, like template.Must. It is meant for templates known in advance.
+func MustTextFormatter(text string) *TextFormatter {
+	formatter, err := NewTextFormatter(text)
+	if err != nil {
+		panic(err)
+	}
+	return formatter
+}
+
+// Format writes the entry followed by a newline.
+func (formatter *TextFormatter) Format(w io.Writer, entry LogEntry) error {
+	line := TextLine{
+		Timestamp: entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		Level:     entry.Level,
+		Message:   strings.TrimRight(entry.Message, "\n"),
+		Index:     entry.Index,
+		Hostname:  entry.Hostname,
+		ID:        entry.ID,
+		Entry:     entry,
+	}
+	if entry.Kubernetes != nil {
+		line.Namespace = entry.Kubernetes.NamespaceName
+		line.Pod = entry.Kubernetes.PodName
+		line.Container = entry.Kubernetes.ContainerName
+	}
+	err := formatter.template.Execute(w, line)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
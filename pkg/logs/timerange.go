@@ -0,0 +1,154 @@
+package logs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeRange is the time range the logs were written in, bounds included. A
+// zero bound leaves the range open on its side.
+type TimeRange struct {
+	Start  time.Time
+	Finish time.Time
+}
+
+var (
+	epochMillisFormat = regexp.MustCompile(`^[0-9]+$`)
+	sinceFormat       = regexp.MustCompile(`^([1-9][0-9]*)(s|m|h|d|w)$`)
+	dateMathOperation = regexp.MustCompile(`^(?:([+-])([0-9]+)|/)([yMwdhHms])`)
+)
+
+// TimeRange resolves the starttime, finishtime and since parameters against
+// now. since, such as 15m or 2h, starts the range that long before now and
+// cannot be given along with starttime.
+func (params Parameters) TimeRange(now time.Time) (TimeRange, error) {
+	var timeRange TimeRange
+	var err error
+	if len(params.StartTime) > 0 {
+		timeRange.Start, err = ParseTime(params.StartTime, now, false)
+		if err != nil {
+			return TimeRange{}, err
+		}
+	}
+	if len(params.Since) > 0 {
+		if len(params.StartTime) > 0 {
+			return TimeRange{}, InvalidTimeRange()
+		}
+		match := sinceFormat.FindStringSubmatch(params.Since)
+		if match == nil {
+			return TimeRange{}, InvalidTimeStamp()
+		}
+		value, err := strconv.Atoi(match[1])
+		if err != nil {
+			return TimeRange{}, InvalidTimeStamp()
+		}
+		timeRange.Start = addDateMath(now.UTC(), -value, match[2])
+	}
+	if len(params.FinishTime) > 0 {
+		timeRange.Finish, err = ParseTime(params.FinishTime, now, true)
+		if err != nil {
+			return TimeRange{}, err
+		}
+	}
+	if !timeRange.Start.IsZero() && !timeRange.Finish.IsZero() && timeRange.Start.After(timeRange.Finish) {
+		return TimeRange{}, InvalidTimeRange()
+	}
+	return timeRange, nil
+}
+
+// Contains reports whether t is in the range.
+func (timeRange TimeRange) Contains(t time.Time) bool {
+	return (timeRange.Start.IsZero() || !t.Before(timeRange.Start)) &&
+		(timeRange.Finish.IsZero() || !t.After(timeRange.Finish))
+}
+
+// ParseTime parses a time given as RFC3339, as milliseconds since the epoch or
+// as Elasticsearch date math relative to now, such as now-1h or now-1d/d.
+// Like the gte and lte bounds of a range query, rounding goes down to the
+// start of the unit for a start time and up to its last millisecond for a
+// finish time, which roundUp asks for.
+func ParseTime(value string, now time.Time, roundUp bool) (time.Time, error) {
+	if epochMillisFormat.MatchString(value) {
+		millis, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, InvalidTimeStamp()
+		}
+		return time.Unix(0, millis*int64(time.Millisecond)).UTC(), nil
+	}
+	if !strings.HasPrefix(value, "now") {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, InvalidTimeStamp()
+		}
+		return t, nil
+	}
+	t := now.UTC()
+	for expression := value[len("now"):]; len(expression) > 0; {
+		match := dateMathOperation.FindStringSubmatch(expression)
+		if match == nil {
+			return time.Time{}, InvalidTimeStamp()
+		}
+		expression = expression[len(match[0]):]
+		if len(match[1]) == 0 {
+			t = roundDateMath(t, match[3], roundUp)
+			continue
+		}
+		amount, err := strconv.Atoi(match[2])
+		if err != nil {
+			return time.Time{}, InvalidTimeStamp()
+		}
+		if match[1] == "-" {
+			amount = -amount
+		}
+		t = addDateMath(t, amount, match[3])
+	}
+	return t, nil
+}
+
+// addDateMath adds amount units to t, y and M being calendar years and months.
+func addDateMath(t time.Time, amount int, unit string) time.Time {
+	switch unit {
+	case "y":
+		return t.AddDate(amount, 0, 0)
+	case "M":
+		return t.AddDate(0, amount, 0)
+	case "w":
+		return t.AddDate(0, 0, 7*amount)
+	case "d":
+		return t.AddDate(0, 0, amount)
+	case "h", "H":
+		return t.Add(time.Duration(amount) * time.Hour)
+	case "m":
+		return t.Add(time.Duration(amount) * time.Minute)
+	}
+	return t.Add(time.Duration(amount) * time.Second)
+}
+
+// roundDateMath rounds t in UTC down to the start of the unit, weeks starting
+// on Monday, or up to the last millisecond of the unit.
+func roundDateMath(t time.Time, unit string, roundUp bool) time.Time {
+	year, month, day := t.Date()
+	var start time.Time
+	switch unit {
+	case "y":
+		start = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	case "M":
+		start = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	case "w":
+		start = time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	case "d":
+		start = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	case "h", "H":
+		start = t.Truncate(time.Hour)
+	case "m":
+		start = t.Truncate(time.Minute)
+	default:
+		start = t.Truncate(time.Second)
+	}
+	if !roundUp {
+		return start
+	}
+	return addDateMath(start, 1, unit).Add(-time.Millisecond)
+}
@@ -0,0 +1,108 @@
+package logs
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultValuesSize = 10
+	MaxValuesSize     = 1000
+)
+
+// ValueFields are the fields whose values can be listed.
+var ValueFields = []string{"namespace", "pod", "container", "label", "level"}
+
+// ValuesOptions narrows down the values listed for a field.
+type ValuesOptions struct {
+	Prefix string `form:"prefix"` // only list values starting with the prefix
+	Size   int    `form:"size"`   // maximum number of values, the most frequent first
+}
+
+// FieldValues are the distinct values of a field in the matching logs.
+type FieldValues struct {
+	Field  string       `json:"field"`
+	Values []FieldValue `json:"values"`
+}
+
+// FieldValue is a value of a field and the number of logs having it.
+type FieldValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Validate checks the field and the options and fills in the default size.
+func (options *ValuesOptions) Validate(field string) error {
+	known := false
+	for _, valueField := range ValueFields {
+		if field == valueField {
+			known = true
+		}
+	}
+	if !known {
+		return InvalidValueField()
+	}
+	if options.Size == 0 {
+		options.Size = DefaultValuesSize
+	}
+	if options.Size < 0 || options.Size > MaxValuesSize {
+		return InvalidValueField()
+	}
+	return nil
+}
+
+// CountValues lists the values of the field in the entries starting with the
+// prefix of the options like a terms aggregation: the most frequent first,
+// ties broken by value. The options must have been validated.
+func CountValues(entries []LogEntry, field string, options ValuesOptions) FieldValues {
+	counts := map[string]int64{}
+	for _, entry := range entries {
+		for _, value := range entry.fieldValues(field) {
+			if len(value) > 0 && strings.HasPrefix(value, options.Prefix) {
+				counts[value]++
+			}
+		}
+	}
+
+	fieldValues := FieldValues{Field: field, Values: []FieldValue{}}
+	for value, count := range counts {
+		fieldValues.Values = append(fieldValues.Values, FieldValue{Value: value, Count: count})
+	}
+	sort.Slice(fieldValues.Values, func(i, j int) bool {
+		a, b := fieldValues.Values[i], fieldValues.Values[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Value < b.Value
+	})
+	if len(fieldValues.Values) > options.Size {
+		fieldValues.Values = fieldValues.Values[:options.Size]
+	}
+	return fieldValues
+}
+
+// fieldValues returns the values of one of the ValueFields or SearchFields of the entry.
+func (entry LogEntry) fieldValues(field string) []string {
+	kubernetes := entry.KubernetesMetadata()
+	switch field {
+	case "namespace":
+		return []string{kubernetes.NamespaceName}
+	case "pod":
+		return []string{kubernetes.PodName}
+	case "container":
+		return []string{kubernetes.ContainerName}
+	case "label":
+		return kubernetes.FlatLabels
+	case "level":
+		return []string{entry.Level}
+	case "hostname":
+		return []string{entry.Hostname}
+	case "viaq_msg_id":
+		return []string{entry.ViaqMsgID}
+	case "index":
+		return []string{entry.Index}
+	case "message":
+		return []string{entry.Message}
+	}
+	return nil
+}
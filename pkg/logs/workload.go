@@ -0,0 +1,58 @@
+package logs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// WorkloadSelector describes the pods owned by a workload through the shape of
+// their names and a label every one of them carries.
+type WorkloadSelector struct {
+	PodNamePattern string // regular expression the whole pod name matches
+	Label          string // flat label, "key=value", set on every pod of the workload
+	LabelPrefix    bool   // the flat label only starts with Label as the rest is generated
+}
+
+var workloadName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// NewWorkloadSelector returns the selector of the pods owned by the workload of the
+// given kind (deployment, statefulset, daemonset, job or cronjob) and name.
+func NewWorkloadSelector(kind string, name string) (WorkloadSelector, error) {
+	if !workloadName.MatchString(name) {
+		return WorkloadSelector{}, InvalidEntity()
+	}
+	prefix := regexp.QuoteMeta(name) + "-"
+	switch strings.TrimSuffix(strings.ToLower(kind), "s") {
+	case "deployment":
+		// <deployment>-<pod-template-hash>-<random>
+		return WorkloadSelector{PodNamePattern: prefix + "[a-z0-9]+-[a-z0-9]+", Label: "pod-template-hash=", LabelPrefix: true}, nil
+	case "statefulset":
+		// <statefulset>-<ordinal>, revisions are named after the statefulset
+		return WorkloadSelector{PodNamePattern: prefix + "[0-9]+", Label: "controller-revision-hash=" + name + "-", LabelPrefix: true}, nil
+	case "daemonset":
+		// <daemonset>-<random>
+		return WorkloadSelector{PodNamePattern: prefix + "[a-z0-9]+", Label: "pod-template-generation=", LabelPrefix: true}, nil
+	case "job":
+		// <job>-<random>
+		return WorkloadSelector{PodNamePattern: prefix + "[a-z0-9]+", Label: "job-name=" + name}, nil
+	case "cronjob":
+		// <cronjob>-<scheduled time>-<random>, jobs are named after the cronjob
+		return WorkloadSelector{PodNamePattern: prefix + "[0-9]+-[a-z0-9]+", Label: "job-name=" + name + "-", LabelPrefix: true}, nil
+	default:
+		return WorkloadSelector{}, InvalidEntity()
+	}
+}
+
+// Matches reports whether a pod with the given name and flat labels belongs to the workload.
+func (selector WorkloadSelector) Matches(podName string, flatLabels []string) bool {
+	matched, _ := regexp.MatchString("^(?:"+selector.PodNamePattern+")$", podName)
+	if !matched {
+		return false
+	}
+	for _, label := range flatLabels {
+		if label == selector.Label || (selector.LabelPrefix && strings.HasPrefix(label, selector.Label)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package loki
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+)
+
+// Stream labels set by the OpenShift Logging collector.
+const (
+	NamespaceLabel = "kubernetes_namespace_name"
+	PodLabel       = "kubernetes_pod_name"
+	ContainerLabel = "kubernetes_container_name"
+	LogTypeLabel   = "log_type"
+	// LevelLabel and the labels of the pod are extracted from the log line by the json parser.
+	LevelLabel       = "level"
+	PodLabelsPrefix  = "kubernetes_labels_"
+	anyLogTypeFilter = `log_type=~".+"`
+)
+
+// logTypes maps the index names to the log_type label values.
+var logTypes = map[string]string{
+	"app":   "application",
+	"infra": "infrastructure",
+	"audit": "audit",
+}
+
+// queryBuilder collects the parts of a LogQL log query.
+type queryBuilder struct {
+	matchers []string // stream selector matchers
+	excluded []string // negative stream selector matchers, which cannot select streams alone
+	filters  []string // line filters
+	parsed   []string // label filters applied after the json parser
+	json     bool     // parse the line even without label filters
+}
+
+// newQueryBuilder starts a query with the filter parameters shared by every method.
+func newQueryBuilder(params logs.Parameters) *queryBuilder {
+	builder := &queryBuilder{}
+	builder.field(NamespaceLabel, params.FieldFilter("namespace"), false)
+	builder.field(PodLabel, params.FieldFilter("pod"), false)
+	builder.field(ContainerLabel, params.FieldFilter("container"), false)
+	if len(params.Index) > 0 {
+		logType, ok := logTypes[params.Index]
+		if !ok {
+			logType = params.Index
+		}
+		builder.equal(LogTypeLabel, logType)
+	}
+	if params.Indices != nil {
+		var visible []string
+		for _, index := range params.Indices {
+			visible = append(visible, regexp.QuoteMeta(logTypes[index]))
+		}
+		builder.matchers = append(builder.matchers, LogTypeLabel+"=~"+strconv.Quote(strings.Join(visible, "|")))
+	}
+	if params.Namespaces != nil {
+		var namespaces []string
+		for _, namespace := range params.Namespaces {
+			namespaces = append(namespaces, regexp.QuoteMeta(namespace))
+		}
+		builder.matchers = append(builder.matchers, NamespaceLabel+"=~"+strconv.Quote(strings.Join(namespaces, "|")))
+	}
+	builder.field(LevelLabel, params.FieldFilter("level"), true)
+	terms, _ := logs.ParseMessageQuery(params.Query)
+	for _, term := range terms {
+		// like the message query on Elasticsearch, terms ignore case
+		operator := "|~"
+		if term.Negate {
+			operator = "!~"
+		}
+		builder.filters = append(builder.filters, operator+" "+strconv.Quote("(?i)"+regexp.QuoteMeta(term.Text)))
+	}
+	return builder
+}
+
+func (builder *queryBuilder) equal(label string, value string) {
+	if len(value) > 0 {
+		builder.matchers = append(builder.matchers, label+"="+strconv.Quote(value))
+	}
+}
+
+// field filters on the values of a label, a stream label or a label
+// extracted by the json parser when parsed is set.
+func (builder *queryBuilder) field(label string, filter logs.FieldFilter, parsed bool) {
+	if len(filter.Include) > 0 {
+		matcher := label + valuesMatcher(filter.Include, "=", "=~")
+		if parsed {
+			builder.parsed = append(builder.parsed, matcher)
+		} else {
+			builder.matchers = append(builder.matchers, matcher)
+		}
+	}
+	if len(filter.Exclude) > 0 {
+		matcher := label + valuesMatcher(filter.Exclude, "!=", "!~")
+		if parsed {
+			builder.parsed = append(builder.parsed, matcher)
+		} else {
+			builder.excluded = append(builder.excluded, matcher)
+		}
+	}
+}
+
+// valuesMatcher returns the operator and value of a matcher on the values:
+// an equality for a single value without wildcards, a regular expression
+// otherwise.
+func valuesMatcher(values []string, equal string, match string) string {
+	if len(values) == 1 && !logs.IsPattern(values[0]) {
+		return equal + strconv.Quote(values[0])
+	}
+	patterns := make([]string, 0, len(values))
+	for _, value := range values {
+		patterns = append(patterns, logs.PatternRegexp(value))
+	}
+	return match + strconv.Quote(strings.Join(patterns, "|"))
+}
+
+// podLabel filters on a label of the pod, given as a flat label "key=value".
+// With prefix set, the value of the label only has to start with value.
+func (builder *queryBuilder) podLabel(flatLabel string, prefix bool) {
+	label, value := podLabelValue(flatLabel)
+	switch {
+	case prefix && len(value) == 0:
+		// labels missing from a line are empty
+		builder.parsed = append(builder.parsed, label+`!=""`)
+	case prefix:
+		builder.parsed = append(builder.parsed, label+"=~"+strconv.Quote(regexp.QuoteMeta(value)+".*"))
+	default:
+		builder.parsed = append(builder.parsed, label+"="+strconv.Quote(value))
+	}
+}
+
+// podLabelValue splits a flat label "key=value" into the label the json
+// parser extracts the label of the pod into and its value.
+func podLabelValue(flatLabel string) (string, string) {
+	key, value := flatLabel, ""
+	if i := strings.Index(flatLabel, "="); i >= 0 {
+		key, value = flatLabel[:i], flatLabel[i+1:]
+	}
+	return PodLabelsPrefix + sanitizeLabel(key), value
+}
+
+// String returns the LogQL query. A stream selector needs at least one
+// matcher that is not empty nor negative, log_type is used when nothing else
+// is selected.
+func (builder *queryBuilder) String() string {
+	matchers := builder.matchers
+	if len(matchers) == 0 {
+		matchers = []string{anyLogTypeFilter}
+	}
+	matchers = append(append([]string{}, matchers...), builder.excluded...)
+	query := "{" + strings.Join(matchers, ", ") + "}"
+	for _, filter := range builder.filters {
+		query += " " + filter
+	}
+	if len(builder.parsed) > 0 || builder.json {
+		query += " | json"
+		for _, filter := range builder.parsed {
+			query += " | " + filter
+		}
+	}
+	return query
+}
+
+// searchLabels maps the fields of a search filter to the labels holding them,
+// all of them available once the json parser ran.
+var searchLabels = map[string]string{
+	"namespace":   NamespaceLabel,
+	"pod":         PodLabel,
+	"container":   ContainerLabel,
+	"level":       LevelLabel,
+	"hostname":    "hostname",
+	"viaq_msg_id": "viaq_msg_id",
+	"index":       LogTypeLabel,
+	"message":     "message",
+}
+
+// searchFilter compiles a validated search filter into a label filter
+// expression, negated when negate is set as LogQL has no not. Labels missing
+// from a line are empty, so that negated conditions match them like a
+// must_not clause does. Time ranges and tests on labels other than eq and in
+// cannot be expressed and are rejected with the path of the condition.
+func searchFilter(condition logs.Condition, path string, negate bool) (string, error) {
+	operator := condition.Operator()
+	switch operator {
+	case "and", "or":
+		children := condition.And
+		if operator == "or" {
+			children = condition.Or
+		}
+		expressions := make([]string, 0, len(children))
+		for i, child := range children {
+			expression, err := searchFilter(child, fmt.Sprintf("%s.%s[%d]", path, operator, i), negate)
+			if err != nil {
+				return "", err
+			}
+			expressions = append(expressions, expression)
+		}
+		// not (a and b) is not a or not b
+		if (operator == "and") == negate {
+			return joinExpressions(expressions, "or"), nil
+		}
+		return joinExpressions(expressions, "and"), nil
+	case "not":
+		return searchFilter(*condition.Not, path+".not", !negate)
+	}
+
+	path += "." + operator
+	label, ok := searchLabels[condition.Field]
+	equal, match, join := "=", "=~", "or"
+	if negate {
+		equal, match, join = "!=", "!~", "and"
+	}
+	if condition.Field == "label" && (operator == "eq" || operator == "in") {
+		values := condition.In
+		if operator == "eq" {
+			values = []string{*condition.Eq}
+		}
+		expressions := make([]string, 0, len(values))
+		for _, value := range values {
+			label, value := podLabelValue(value)
+			expressions = append(expressions, label+equal+strconv.Quote(value))
+		}
+		return joinExpressions(expressions, join), nil
+	}
+	if !ok || operator == "range" || (condition.Field == "index" && operator == "prefix") {
+		return "", logs.InvalidSearch(path, fmt.Sprintf("%s on %s is not supported with Loki", operator, condition.Field))
+	}
+	switch operator {
+	case "eq":
+		return label + equal + strconv.Quote(labelValue(condition.Field, *condition.Eq)), nil
+	case "in":
+		patterns := make([]string, 0, len(condition.In))
+		for _, value := range condition.In {
+			patterns = append(patterns, regexp.QuoteMeta(labelValue(condition.Field, value)))
+		}
+		return label + match + strconv.Quote(strings.Join(patterns, "|")), nil
+	case "prefix":
+		return label + match + strconv.Quote(regexp.QuoteMeta(*condition.Prefix)+".*"), nil
+	case "exists":
+		if *condition.Exists != negate {
+			return label + `!=""`, nil
+		}
+		return label + `=""`, nil
+	}
+
+	// like the message query on Elasticsearch, terms ignore case
+	terms, _ := logs.ParseMessageQuery(*condition.Match)
+	expressions := make([]string, 0, len(terms))
+	for _, term := range terms {
+		operator := "=~"
+		if term.Negate != negate {
+			operator = "!~"
+		}
+		expressions = append(expressions, label+operator+strconv.Quote("(?is).*"+regexp.QuoteMeta(term.Text)+".*"))
+	}
+	if negate {
+		return joinExpressions(expressions, "or"), nil
+	}
+	return joinExpressions(expressions, "and"), nil
+}
+
+// joinExpressions combines label filter expressions with and or or.
+func joinExpressions(expressions []string, operator string) string {
+	if len(expressions) == 1 {
+		return expressions[0]
+	}
+	return "(" + strings.Join(expressions, " "+operator+" ") + ")"
+}
+
+// labelValue returns the label value of a value of a search field, the
+// log_type of an index.
+func labelValue(field string, value string) string {
+	if logType, ok := logTypes[value]; ok && field == "index" {
+		return logType
+	}
+	return value
+}
+
+var invalidLabelCharacters = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// sanitizeLabel turns a JSON key into the label name the json parser gives it.
+func sanitizeLabel(key string) string {
+	return invalidLabelCharacters.ReplaceAllString(key, "_")
+}
@@ -0,0 +1,606 @@
+package loki
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ViaQ/log-exploration-api/pkg/configuration"
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"go.uber.org/zap"
+)
+
+// defaultLookback is how far back logs are searched when no time range is
+// given, since Loki needs a start time.
+const defaultLookback = 7 * 24 * time.Hour
+
+// LokiRepository reads logs from the Loki HTTP API, such as the gateway of a
+// LokiStack, by translating the filter parameters into LogQL.
+type LokiRepository struct {
+	client  *http.Client
+	address string
+	log     *zap.Logger
+	now     func() time.Time
+}
+
+func NewLokiRepository(log *zap.Logger, config *configuration.LokiConfig) (logs.LogsProvider, error) {
+	client := &http.Client{}
+	if len(config.CAFile) > 0 {
+		ca, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			log.Error("failed to read the Loki CA", zap.Error(err))
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no certificate found in " + config.CAFile)
+		}
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	}
+	repository := &LokiRepository{
+		client:  client,
+		address: strings.TrimSuffix(config.Address, "/"),
+		log:     log,
+		now:     time.Now,
+	}
+	return repository, nil
+}
+
+func (repository *LokiRepository) CheckReadiness(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, repository.address+"/ready", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := repository.client.Do(req)
+	if err != nil {
+		repository.log.Error("error while connecting to loki to retrieve the readiness", zap.Error(err))
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func (repository *LokiRepository) FilterLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return repository.queryLogs(ctx, params, newQueryBuilder(params))
+}
+
+func (repository *LokiRepository) FilterContainerLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return repository.queryLogs(ctx, params, newQueryBuilder(params))
+}
+
+func (repository *LokiRepository) FilterNamespaceLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return repository.queryLogs(ctx, params, newQueryBuilder(params))
+}
+
+func (repository *LokiRepository) FilterPodLogs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return repository.queryLogs(ctx, params, newQueryBuilder(params))
+}
+
+func (repository *LokiRepository) Logs(ctx context.Context, params logs.Parameters) (logs.Page, error) {
+	return repository.queryLogs(ctx, params, newQueryBuilder(params))
+}
+
+func (repository *LokiRepository) FilterLabelLogs(ctx context.Context, params logs.Parameters, labelList []string) (logs.Page, error) {
+	builder := newQueryBuilder(params)
+	for _, label := range labelList {
+		builder.podLabel(label, false)
+	}
+	return repository.queryLogs(ctx, params, builder)
+}
+
+func (repository *LokiRepository) FilterEntityLogs(ctx context.Context, params logs.Parameters, kind string, name string) (logs.Page, error) {
+	selector, err := logs.NewWorkloadSelector(kind, name)
+	if err != nil {
+		repository.log.Error("Invalid entity:", zap.Error(err))
+		return logs.Page{}, err
+	}
+	builder := newQueryBuilder(params)
+	builder.matchers = append(builder.matchers, PodLabel+"=~"+strconv.Quote(selector.PodNamePattern))
+	builder.podLabel(selector.Label, selector.LabelPrefix)
+	return repository.queryLogs(ctx, params, builder)
+}
+
+// Search adds the filter tree as a label filter expression after the json parser.
+func (repository *LokiRepository) Search(ctx context.Context, params logs.Parameters, filter logs.Condition) (logs.Page, error) {
+	err := filter.Validate()
+	if err != nil {
+		repository.log.Error("Invalid search filter:", zap.Error(err))
+		return logs.Page{}, err
+	}
+	expression, err := searchFilter(filter, "filter", false)
+	if err != nil {
+		repository.log.Error("Invalid search filter:", zap.Error(err))
+		return logs.Page{}, err
+	}
+	builder := newQueryBuilder(params)
+	builder.parsed = append(builder.parsed, expression)
+	return repository.queryLogs(ctx, params, builder)
+}
+
+// Export pages through the logs matching the filter parameters of FilterLogs.
+func (repository *LokiRepository) Export(ctx context.Context, params logs.Parameters, handle func([]logs.LogEntry) error) error {
+	batchSize := logs.ExportBatchSize
+	if batchSize > logs.MaxLogsLimit {
+		// the batches are pages, which must stay within the configured limit
+		batchSize = logs.MaxLogsLimit
+	}
+	params.MaxLogs = strconv.Itoa(batchSize)
+	params.Cursor = ""
+	builder := newQueryBuilder(params)
+	for {
+		page, err := repository.queryLogs(ctx, params, builder)
+		if err != nil {
+			return err
+		}
+		if len(page.Logs) > 0 {
+			err = handle(page.Logs)
+			if err != nil {
+				return err
+			}
+		}
+		if len(page.NextCursor) == 0 {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		params.Cursor = page.NextCursor
+	}
+}
+
+// Context is not supported: Loki has no document ids to find the entry by.
+func (repository *LokiRepository) Context(ctx context.Context, params logs.Parameters, id string, options logs.ContextOptions) (logs.LogContext, error) {
+	return logs.LogContext{}, logs.NewError(logs.KindValidation, "the context of a log cannot be fetched from Loki", nil)
+}
+
+// Histogram counts the logs per time bucket with a count_over_time metric query.
+func (repository *LokiRepository) Histogram(ctx context.Context, params logs.Parameters, options logs.HistogramOptions) (logs.Histogram, error) {
+	err := params.Validate()
+	if err == nil {
+		err = options.Validate()
+	}
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.Histogram{}, err
+	}
+	start, end := repository.timeRange(params)
+	interval := options.Interval
+	var from, to time.Time
+	if len(interval) > 0 {
+		requested, _ := params.TimeRange(repository.now())
+		from, to = requested.Start, requested.Finish
+	} else {
+		interval = logs.AutoInterval(start, end, options.Buckets)
+	}
+	width, _ := logs.ParseInterval(interval)
+	// like Elasticsearch, only the requested time range is checked
+	err = logs.CheckBuckets(width, from, to)
+	if err != nil {
+		return logs.Histogram{}, err
+	}
+	if hidesEverything(params) {
+		filled, err := logs.FillBuckets(nil, width, from, to)
+		return logs.Histogram{Interval: interval, Buckets: filled}, err
+	}
+
+	builder := newQueryBuilder(params)
+	by := ""
+	switch options.SplitBy {
+	case "level":
+		by = " by (" + LevelLabel + ")"
+		builder.json = true
+	case "index":
+		by = " by (" + LogTypeLabel + ")"
+	}
+	// the sample at t counts the logs of the bucket ending at t
+	first := start.UnixNano() - start.UnixNano()%int64(width) + int64(width)
+	last := end.UnixNano() - end.UnixNano()%int64(width) + int64(width)
+	values := url.Values{
+		"query": {fmt.Sprintf("sum%s (count_over_time(%s [%s]))", by, builder.String(), interval)},
+		"start": {strconv.FormatInt(first, 10)},
+		"end":   {strconv.FormatInt(last, 10)},
+		"step":  {strconv.FormatFloat(width.Seconds(), 'f', -1, 64)},
+	}
+	var series []struct {
+		Metric map[string]string `json:"metric"`
+		Values [][2]interface{}  `json:"values"`
+	}
+	err = repository.get(ctx, params.Token, "/loki/api/v1/query_range", values, &series)
+	if err != nil {
+		return logs.Histogram{}, err
+	}
+
+	counts := map[int64]*logs.HistogramBucket{}
+	for _, serie := range series {
+		for _, value := range serie.Values {
+			timestamp, count := sampleValue(value)
+			key := timestamp.Add(-width).UnixNano()
+			bucket, ok := counts[key]
+			if !ok {
+				bucket = &logs.HistogramBucket{Timestamp: time.Unix(0, key).UTC()}
+				counts[key] = bucket
+			}
+			bucket.Count += count
+			switch options.SplitBy {
+			case "level":
+				addSplit(bucket, serie.Metric[LevelLabel], count)
+			case "index":
+				addSplit(bucket, indexName(serie.Metric[LogTypeLabel]), count)
+			}
+		}
+	}
+	var buckets []logs.HistogramBucket
+	for _, bucket := range counts {
+		buckets = append(buckets, *bucket)
+	}
+	filled, err := logs.FillBuckets(buckets, width, from, to)
+	if err != nil {
+		return logs.Histogram{}, err
+	}
+	return logs.Histogram{Interval: interval, Buckets: filled}, nil
+}
+
+// Values counts the logs per value of a stream label, or of the level parsed
+// from the line, with an instant count_over_time metric query.
+func (repository *LokiRepository) Values(ctx context.Context, params logs.Parameters, field string, options logs.ValuesOptions) (logs.FieldValues, error) {
+	err := params.Validate()
+	if err == nil {
+		err = options.Validate(field)
+	}
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.FieldValues{}, err
+	}
+	builder := newQueryBuilder(params)
+	var label string
+	switch field {
+	case "namespace":
+		label = NamespaceLabel
+	case "pod":
+		label = PodLabel
+	case "container":
+		label = ContainerLabel
+	case "level":
+		label = LevelLabel
+		builder.json = true
+	default:
+		return logs.FieldValues{}, logs.NewError(logs.KindValidation, "the values of \""+field+"\" cannot be listed with Loki", nil)
+	}
+	if hidesEverything(params) {
+		return logs.FieldValues{Field: field, Values: []logs.FieldValue{}}, nil
+	}
+	start, end := repository.timeRange(params)
+	values := url.Values{
+		"query": {fmt.Sprintf("sum by (%s) (count_over_time(%s [%ds]))", label, builder.String(), int64(end.Sub(start).Seconds()))},
+		"time":  {strconv.FormatInt(end.UnixNano(), 10)},
+	}
+	var vector []struct {
+		Metric map[string]string `json:"metric"`
+		Value  [2]interface{}    `json:"value"`
+	}
+	err = repository.get(ctx, params.Token, "/loki/api/v1/query", values, &vector)
+	if err != nil {
+		return logs.FieldValues{}, err
+	}
+
+	fieldValues := logs.FieldValues{Field: field, Values: []logs.FieldValue{}}
+	for _, sample := range vector {
+		value := sample.Metric[label]
+		if len(value) == 0 || !strings.HasPrefix(value, options.Prefix) {
+			continue
+		}
+		_, count := sampleValue(sample.Value)
+		fieldValues.Values = append(fieldValues.Values, logs.FieldValue{Value: value, Count: count})
+	}
+	sort.Slice(fieldValues.Values, func(i, j int) bool {
+		a, b := fieldValues.Values[i], fieldValues.Values[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Value < b.Value
+	})
+	if len(fieldValues.Values) > options.Size {
+		fieldValues.Values = fieldValues.Values[:options.Size]
+	}
+	return fieldValues, nil
+}
+
+// hidesEverything reports whether the caller can see no namespace or no
+// index, which a stream selector cannot express.
+func hidesEverything(params logs.Parameters) bool {
+	return params.Namespaces != nil && len(params.Namespaces) == 0 ||
+		params.Indices != nil && len(params.Indices) == 0
+}
+
+// queryLogs runs a log query and returns a page ordered like the
+// Elasticsearch provider does, newest first unless ascending order is asked.
+func (repository *LokiRepository) queryLogs(ctx context.Context, params logs.Parameters, builder *queryBuilder) (logs.Page, error) {
+	err := params.Validate()
+	if err != nil {
+		repository.log.Error("Invalid Query Parameters:", zap.Error(err))
+		return logs.Page{}, err
+	}
+	if hidesEverything(params) {
+		return logs.Page{}, nil
+	}
+	maxEntries := params.PageSize()
+	ascending := params.Order == "asc"
+	start, end := repository.timeRange(params)
+	var cursor logs.Cursor
+	if len(params.Cursor) > 0 {
+		cursor, _ = logs.ParseCursor(params.Cursor)
+		// entries sharing the timestamp of the cursor are fetched again and skipped below
+		if ascending {
+			start = cursor.Timestamp
+		} else {
+			end = cursor.Timestamp.Add(time.Nanosecond)
+		}
+	}
+	direction := "backward"
+	if ascending {
+		direction = "forward"
+	}
+
+	// Loki returns the first entries up to the limit, which may stop in the
+	// middle of the entries sharing a timestamp, and those up to the cursor
+	// are skipped: the limit is widened until enough complete entries follow
+	// the cursor, up to maxFetchLimit.
+	var entries []logs.LogEntry
+	more := false
+	for limit := maxEntries + 1; ; limit = minInt(2*limit, maxFetchLimit()) { // one extra entry tells whether there is a next page
+		fetched, err := repository.fetchEntries(ctx, params, builder, start, end, limit, direction)
+		if err != nil {
+			return logs.Page{}, err
+		}
+		entries = entries[:0]
+		var boundary time.Time
+		for _, entry := range fetched {
+			if boundary.IsZero() || entry.Timestamp.After(boundary) == ascending {
+				boundary = entry.Timestamp
+			}
+			if len(params.Cursor) == 0 || after(entry, cursor, ascending) {
+				entries = append(entries, entry)
+			}
+		}
+		if len(fetched) < limit {
+			break
+		}
+		var complete []logs.LogEntry
+		for _, entry := range entries {
+			if !entry.Timestamp.Equal(boundary) {
+				complete = append(complete, entry)
+			}
+		}
+		if len(complete) >= maxEntries {
+			// the entries at the boundary follow the page
+			entries, more = complete, true
+			break
+		}
+		if limit >= maxFetchLimit() {
+			return logs.Page{}, logs.NewError(logs.KindValidation, fmt.Sprintf("more than %d logs share the timestamps of the page, please narrow the filter", maxFetchLimit()), nil)
+		}
+	}
+	logs.SortEntries(params.Order, entries)
+
+	var page logs.Page
+	for i, entry := range entries {
+		if i == maxEntries {
+			page.NextCursor = page.EndCursor
+			break
+		}
+		page.Logs = append(page.Logs, entry)
+		page.EndCursor = logs.EntryCursor(entry).Encode()
+	}
+	if more {
+		page.NextCursor = page.EndCursor
+	}
+	page.Logs = logs.ProjectEntries(page.Logs, params.Projection())
+	return page, nil
+}
+
+// maxFetchLimit is the largest number of entries fetched for a page, which
+// stays below the default max_entries_limit_per_query of Loki.
+func maxFetchLimit() int {
+	return 4 * logs.MaxLogsLimit
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// fetchEntries runs a log query over the time range, returning up to limit
+// entries in the given direction.
+func (repository *LokiRepository) fetchEntries(ctx context.Context, params logs.Parameters, builder *queryBuilder, start time.Time, end time.Time, limit int, direction string) ([]logs.LogEntry, error) {
+	values := url.Values{
+		"query":     {builder.String()},
+		"start":     {strconv.FormatInt(start.UnixNano(), 10)},
+		"end":       {strconv.FormatInt(end.UnixNano(), 10)},
+		"limit":     {strconv.Itoa(limit)},
+		"direction": {direction},
+	}
+	var streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	err := repository.get(ctx, params.Token, "/loki/api/v1/query_range", values, &streams)
+	if err != nil {
+		return nil, err
+	}
+	var entries []logs.LogEntry
+	for _, stream := range streams {
+		for _, value := range stream.Values {
+			entry, err := newLogEntry(stream.Stream, value)
+			if err != nil {
+				repository.log.Error("Error occurred while decoding the log line", zap.Error(err))
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// after reports whether the entry comes after the cursor in the given order.
+func after(entry logs.LogEntry, cursor logs.Cursor, ascending bool) bool {
+	if entry.Timestamp.Equal(cursor.Timestamp) {
+		return cursor.Precedes(entry)
+	}
+	return entry.Timestamp.After(cursor.Timestamp) == ascending
+}
+
+// timeRange returns the requested time range, open bounds ending now and
+// starting the default lookback before the end.
+func (repository *LokiRepository) timeRange(params logs.Parameters) (time.Time, time.Time) {
+	now := repository.now()
+	requested, _ := params.TimeRange(now)
+	start, end := requested.Start, requested.Finish
+	if end.IsZero() {
+		end = now
+	}
+	if start.IsZero() {
+		start = end.Add(-defaultLookback)
+	}
+	return start, end
+}
+
+// get sends a query with the bearer token of the caller and decodes the
+// result of the response into result.
+func (repository *LokiRepository) get(ctx context.Context, token map[string]string, path string, values url.Values, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, repository.address+path+"?"+values.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range token {
+		req.Header.Set(key, value)
+	}
+	resp, err := repository.client.Do(req)
+	if err != nil {
+		repository.log.Error("failed exec Loki query", zap.Error(err))
+		if errors.Is(err, context.DeadlineExceeded) {
+			return logs.NewError(logs.KindTimeout, "an error occurred while fetching logs: the query timed out", err)
+		}
+		return logs.NewError(logs.KindUnavailable, "an error occurred while fetching logs: Loki cannot be reached", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		repository.log.Error("An error occurred while fetching logs", zap.Int("status", resp.StatusCode), zap.ByteString("body", body))
+		return responseError(resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var response struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		repository.log.Error("Error occurred while decoding JSON", zap.Error(err))
+		return err
+	}
+	if response.Status != "success" {
+		return logs.NewError(logs.KindUnavailable, "an error occurred while fetching logs: unexpected response from Loki", nil)
+	}
+	return json.Unmarshal(response.Data.Result, result)
+}
+
+// responseError returns the error reported to the client when Loki, or the
+// gateway in front of it, answers with an error status, which is kept.
+func responseError(status int, body string) error {
+	switch status {
+	case http.StatusBadRequest:
+		return logs.NewError(logs.KindValidation, body, nil)
+	case http.StatusUnauthorized:
+		return logs.NewError(logs.KindUnauthorized, "the token was not accepted by Loki", nil)
+	case http.StatusForbidden:
+		return logs.NewError(logs.KindForbidden, "the caller may not read these logs from Loki", nil)
+	case http.StatusNotFound:
+		return logs.NewError(logs.KindNotFound, "Loki answered "+http.StatusText(status), nil)
+	case http.StatusGatewayTimeout:
+		return logs.NewError(logs.KindTimeout, "an error occurred while fetching logs: the query timed out", nil)
+	}
+	return logs.NewError(logs.KindUnavailable, "an error occurred while fetching logs: Loki answered "+http.StatusText(status), nil)
+}
+
+// newLogEntry converts a line of a stream into a LogEntry. The collector
+// writes ViaQ records as lines, anything else is kept as the message.
+func newLogEntry(labels map[string]string, value [2]string) (logs.LogEntry, error) {
+	nanoseconds, err := strconv.ParseInt(value[0], 10, 64)
+	if err != nil {
+		return logs.LogEntry{}, err
+	}
+	line := value[1]
+	// Loki has no document ids, the stream and the line identify an entry
+	hash := fnv.New64a()
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s=%s,", key, labels[key])
+	}
+	hash.Write([]byte(line))
+	id := fmt.Sprintf("%d-%016x", nanoseconds, hash.Sum64())
+	index := indexName(labels[LogTypeLabel])
+
+	entry, err := logs.NewLogEntry(id, index, []byte(line))
+	if err != nil {
+		entry = logs.LogEntry{ID: id, Index: index, Message: line}
+	}
+	entry.Timestamp = time.Unix(0, nanoseconds).UTC()
+	if entry.Kubernetes == nil && len(labels[NamespaceLabel]) > 0 {
+		entry.Kubernetes = &logs.KubernetesMetadata{
+			NamespaceName: labels[NamespaceLabel],
+			PodName:       labels[PodLabel],
+			ContainerName: labels[ContainerLabel],
+		}
+	}
+	if len(entry.Level) == 0 {
+		entry.Level = labels[LevelLabel]
+	}
+	entry.Hit = line
+	return entry, nil
+}
+
+// indexName returns the index name of a log_type label value.
+func indexName(logType string) string {
+	for index, value := range logTypes {
+		if value == logType {
+			return index
+		}
+	}
+	return logType
+}
+
+// sampleValue decodes a [timestamp, "value"] pair of a metric query result.
+func sampleValue(value [2]interface{}) (time.Time, int64) {
+	seconds, _ := value[0].(float64)
+	text, _ := value[1].(string)
+	count, _ := strconv.ParseFloat(text, 64)
+	milliseconds := int64(math.Round(seconds * 1000)) // samples are aligned on milliseconds at most
+	return time.Unix(0, milliseconds*int64(time.Millisecond)).UTC(), int64(count)
+}
+
+func addSplit(bucket *logs.HistogramBucket, key string, count int64) {
+	if bucket.Split == nil {
+		bucket.Split = map[string]int64{}
+	}
+	bucket.Split[key] += count
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ViaQ/log-exploration-api/pkg/authorization"
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	namespacesKey = "namespaces"
+	indicesKey    = "indices"
+)
+
+// Authorization only lets callers read the logs of namespaces in which they may
+// get pods/log. Requests for namespaces, as a path or query parameter, are
+// rejected when that is not allowed for one of them and limited to them
+// otherwise; other requests, including those for namespace patterns such as
+// openshift-*, are limited to the namespaces visible to the caller. Likewise requests for an index the caller
+// may not read are rejected and the others are limited to the visible indices.
+func Authorization(authorizer *authorization.Authorizer) gin.HandlerFunc {
+	return func(gctx *gin.Context) {
+		ctx := gctx.Request.Context()
+		token := strings.TrimPrefix(gctx.GetHeader("Authorization"), "Bearer ")
+		user, err := authorizer.Authenticate(ctx, token)
+		if errors.Is(err, authorization.ErrUnauthenticated) {
+			AbortWithProblem(gctx, logs.NewError(logs.KindUnauthorized, "please pass a valid token: "+err.Error(), err))
+			return
+		}
+		if err != nil {
+			abortUnavailable(gctx, err)
+			return
+		}
+
+		indices, err := authorizer.VisibleIndices(ctx, user)
+		if err != nil {
+			abortUnavailable(gctx, err)
+			return
+		}
+		if indices != nil {
+			if index := gctx.Query("index"); len(index) > 0 && !logs.InIndices(index, indices) {
+				AbortWithProblem(gctx, logs.NewError(logs.KindForbidden, "user \""+user.Username+"\" cannot read the logs of index \""+index+"\"", nil))
+				return
+			}
+			gctx.Set(indicesKey, indices)
+		}
+
+		namespace := gctx.Param("namespace")
+		if len(namespace) == 0 {
+			namespace = gctx.Query("namespace")
+		}
+		if namespaces := logs.SplitValues(namespace); len(namespaces) > 0 && !hasPattern(namespaces) {
+			for _, namespace := range namespaces {
+				allowed, err := authorizer.CanGetPodLogs(ctx, user, namespace)
+				if err != nil {
+					abortUnavailable(gctx, err)
+					return
+				}
+				if !allowed {
+					AbortWithProblem(gctx, logs.NewError(logs.KindForbidden, "user \""+user.Username+"\" cannot get pods/log in namespace \""+namespace+"\"", nil))
+					return
+				}
+			}
+			// Handlers such as /logs drop the namespace parameter, so the
+			// query is always limited to the namespaces that were allowed.
+			gctx.Set(namespacesKey, namespaces)
+			gctx.Next()
+			return
+		}
+
+		namespaces, err := authorizer.VisibleNamespaces(ctx, user)
+		if err != nil {
+			abortUnavailable(gctx, err)
+			return
+		}
+		if namespaces != nil {
+			gctx.Set(namespacesKey, namespaces)
+		}
+		gctx.Next()
+	}
+}
+
+// hasPattern reports whether one of the values has wildcards.
+func hasPattern(values []string) bool {
+	for _, value := range values {
+		if logs.IsPattern(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// abortUnavailable answers when the Kubernetes API server could not tell
+// whether the request is allowed.
+func abortUnavailable(gctx *gin.Context, err error) {
+	AbortWithProblem(gctx, logs.NewError(logs.KindUnavailable, "unable to authorize the request: "+err.Error(), err))
+}
+
+// AllowedNamespaces returns the namespaces the request is limited to, or nil
+// when it is not limited.
+func AllowedNamespaces(gctx *gin.Context) []string {
+	namespaces, ok := gctx.Get(namespacesKey)
+	if !ok {
+		return nil
+	}
+	return namespaces.([]string)
+}
+
+// AllowedIndices returns the indices the request is limited to, or nil when it
+// is not limited.
+func AllowedIndices(gctx *gin.Context) []string {
+	indices, ok := gctx.Get(indicesKey)
+	if !ok {
+		return nil
+	}
+	return indices.([]string)
+}
@@ -1,13 +1,30 @@
 package middleware
 
 import (
-	"github.com/gin-gonic/gin"
 	"strings"
+
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/gin-gonic/gin"
 )
 
-func AddHeader() gin.HandlerFunc {
+// AddHeader sets the CORS headers. Any origin may make cross-origin requests
+// when the allowed origins contain *, otherwise only the listed ones may.
+func AddHeader(allowedOrigins []string) gin.HandlerFunc {
+	anyOrigin := false
+	allowed := map[string]bool{}
+	for _, origin := range allowedOrigins {
+		anyOrigin = anyOrigin || origin == "*"
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
 	return func(gctx *gin.Context) {
-		gctx.Header("Access-Control-Allow-Origin", "*")
+		if anyOrigin {
+			gctx.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			gctx.Header("Vary", "Origin")
+			if origin := gctx.GetHeader("Origin"); allowed[origin] {
+				gctx.Header("Access-Control-Allow-Origin", origin)
+			}
+		}
 		gctx.Header("Access-Control-Allow-Methods", "DELETE, POST, GET, OPTIONS")
 		gctx.Header("Access-Control-Allow-Headers", "Content-Type, Access-Control-Allow-Headers, Authorization, X-Requested-With")
 		if gctx.Request.Method == "OPTIONS" {
@@ -21,7 +38,8 @@ func TokenHeader() gin.HandlerFunc {
 	return func(gctx *gin.Context) {
 		tokenValue := gctx.Request.Header["Authorization"]
 		if len(tokenValue) == 0 || len(strings.Split(tokenValue[0], "Bearer ")) <= 1 {
-			gctx.AbortWithStatusJSON(401, map[string][]string{"Unauthorized, Please pass the token": {"authorization token not found"}})
+			AbortWithProblem(gctx, logs.NewError(logs.KindUnauthorized, "please pass the token: authorization token not found", nil))
+			return
 		}
 		gctx.Next()
 	}
@@ -0,0 +1,14 @@
+package middleware
+
+import (
+	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/gin-gonic/gin"
+)
+
+// AbortWithProblem stops the request and answers with the problem details of
+// err, whose kind decides the status code.
+func AbortWithProblem(gctx *gin.Context, err error) {
+	problem := logs.NewProblem(err)
+	gctx.Header("Content-Type", logs.ProblemContentType)
+	gctx.AbortWithStatusJSON(problem.Status, problem)
+}
@@ -1,6 +1,7 @@
 package e2e
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
@@ -8,6 +9,7 @@ import (
 	"github.com/ViaQ/log-exploration-api/pkg/configuration"
 	"github.com/ViaQ/log-exploration-api/pkg/elastic"
 	"github.com/ViaQ/log-exploration-api/pkg/logs"
+	"github.com/ViaQ/log-exploration-api/pkg/logs/logstest"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -17,7 +19,10 @@ var esRepository logs.LogsProvider
 func TestMain(m *testing.M) {
 	log, _ := initCustomZapLogger("info")
 
-	appConf := configuration.ParseArgs()
+	appConf, err := configuration.ParseArgs()
+	if err != nil {
+		log.Fatal("invalid configuration", zap.Error(err))
+	}
 	esRepository, _ = elastic.NewElasticRepository(log.Named("elasticsearch"), appConf.Elasticsearch)
 	os.Exit(m.Run())
 }
@@ -39,21 +44,23 @@ func initRepository(t *testing.T, tt testStruct) (logs.LogsProvider, logs.Parame
 	return repository, params
 }
 
-func errorHandler(t *testing.T, testError, err error, testKeywords, logList []string, testName string) {
+func errorHandler(t *testing.T, testError, err error, testKeywords []string, logList []logs.LogEntry, testName string) {
 	if err == nil && testError != nil {
 		t.Errorf("Expected error is: %v, found %v", testError, err)
 	} else if err != nil && testError == nil {
 		t.Errorf("Expected error is: %v, found %v", testError, err)
 	} else if err != nil && testError != nil && err.Error() != testError.Error() {
 		t.Errorf("Expected error is: %v, found %v", testError, err)
-	} else if logList != nil {
+	} else if err == nil {
 		if strings.Contains(testName, "Invalid") || strings.Contains(testName, "No logs") {
-			if !strings.Contains(logList[0], "No logs are present or the entry does not exist") {
+			if len(logList) != 0 {
 				t.Errorf("Expected response: No logs are present or the entry does not exist")
 			}
+		} else if len(logList) == 0 {
+			t.Errorf("Expected logs, found none")
 		} else {
 			for _, keyword := range testKeywords {
-				if !strings.Contains(logList[0], keyword) {
+				if !strings.Contains(logList[0].Hit, keyword) {
 					t.Errorf("Invalid logs found!")
 				}
 			}
@@ -132,8 +139,8 @@ func TestFilterPodLogs(t *testing.T) {
 
 	for _, tt := range tests {
 		repository, params := initRepository(t, tt)
-		logList, err := repository.FilterPodLogs(params)
-		errorHandler(t, tt.TestError, err, tt.TestKeywords, logList, tt.TestName)
+		page, err := repository.FilterPodLogs(context.Background(), params)
+		errorHandler(t, tt.TestError, err, tt.TestKeywords, page.Logs, tt.TestName)
 	}
 }
 
@@ -220,8 +227,8 @@ func TestFilterLogsByLabel(t *testing.T) {
 		repository := esRepository
 		params := logs.Parameters{}
 		addParams(&params, tt.TestParams)
-		logList, err := repository.FilterLabelLogs(params, tt.LabelList)
-		errorHandler(t, tt.TestError, err, tt.TestKeywords, logList, tt.TestName)
+		page, err := repository.FilterLabelLogs(context.Background(), params, tt.LabelList)
+		errorHandler(t, tt.TestError, err, tt.TestKeywords, page.Logs, tt.TestName)
 	}
 }
 
@@ -290,11 +297,163 @@ func TestFilterNamespaceLogs(t *testing.T) {
 
 	for _, tt := range tests {
 		repository, params := initRepository(t, tt)
-		logList, err := repository.FilterNamespaceLogs(params)
+		page, err := repository.FilterNamespaceLogs(context.Background(), params)
+		errorHandler(t, tt.TestError, err, tt.TestKeywords, page.Logs, tt.TestName)
+	}
+}
+
+func TestFilterEntityLogs(t *testing.T) {
+
+	tests := []struct {
+		testStruct
+		Entity     string
+		EntityName string
+	}{
+		{
+			testStruct{
+				"Filter Deployment Logs",
+				false,
+				map[string]string{"Namespace": "openshift-image-registry"},
+				nil,
+				[]string{"openshift-image-registry", "image-registry-78b76b488f"},
+			},
+			"deployment",
+			"image-registry",
+		},
+		{
+			testStruct{
+				"Invalid Deployment, or Deployment for which no logs exist",
+				false,
+				map[string]string{"Namespace": "openshift-image-registry"},
+				nil,
+				[]string{},
+			},
+			"deployment",
+			"image",
+		},
+		{
+			testStruct{
+				"Invalid entity",
+				false,
+				map[string]string{"Namespace": "openshift-image-registry"},
+				logs.InvalidEntity(),
+				[]string{},
+			},
+			"replicaset",
+			"image-registry-78b76b488f",
+		},
+	}
+
+	for _, tt := range tests {
+		repository, params := initRepository(t, tt.testStruct)
+		page, err := repository.FilterEntityLogs(context.Background(), params, tt.Entity, tt.EntityName)
+		errorHandler(t, tt.TestError, err, tt.TestKeywords, page.Logs, tt.TestName)
+	}
+}
+
+func TestValues(t *testing.T) {
+
+	tests := []struct {
+		testStruct
+		Field   string
+		Options logs.ValuesOptions
+	}{
+		{
+			testStruct{
+				"Namespaces starting with a prefix",
+				false,
+				map[string]string{},
+				nil,
+				[]string{"openshift-image-registry"},
+			},
+			"namespace",
+			logs.ValuesOptions{Prefix: "openshift-image"},
+		},
+		{
+			testStruct{
+				"Prefix matching no value",
+				false,
+				map[string]string{},
+				nil,
+				[]string{},
+			},
+			"namespace",
+			logs.ValuesOptions{Prefix: "no-such-namespace"},
+		},
+		{
+			testStruct{
+				"Unknown field",
+				false,
+				map[string]string{},
+				logs.InvalidValueField(),
+				[]string{},
+			},
+			"hostname",
+			logs.ValuesOptions{},
+		},
+	}
+
+	for _, tt := range tests {
+		repository, params := initRepository(t, tt.testStruct)
+		values, err := repository.Values(context.Background(), params, tt.Field, tt.Options)
+		if tt.TestError != nil {
+			if err == nil || err.Error() != tt.TestError.Error() {
+				t.Errorf("%s: expected error %v, got %v", tt.TestName, tt.TestError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.TestName, err)
+			continue
+		}
+		if len(values.Values) != len(tt.TestKeywords) {
+			t.Errorf("%s: expected values %v, got %v", tt.TestName, tt.TestKeywords, values.Values)
+			continue
+		}
+		for i, keyword := range tt.TestKeywords {
+			if values.Values[i].Value != keyword {
+				t.Errorf("%s: expected values %v, got %v", tt.TestName, tt.TestKeywords, values.Values)
+			}
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+
+	tests := []testStruct{
+		{
+			"Export namespace logs",
+			false,
+			map[string]string{"Namespace": "openshift-image-registry"},
+			nil,
+			[]string{"openshift-image-registry"},
+		},
+		{
+			"Export logs of a namespace without logs",
+			false,
+			map[string]string{"Namespace": "no-such-namespace"},
+			logs.NotFoundError(),
+			[]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		repository, params := initRepository(t, tt)
+		var logList []logs.LogEntry
+		err := repository.Export(context.Background(), params, func(entries []logs.LogEntry) error {
+			logList = append(logList, entries...)
+			return nil
+		})
 		errorHandler(t, tt.TestError, err, tt.TestKeywords, logList, tt.TestName)
 	}
 }
 
+// TestConformance runs the table the mocked provider is checked against, so
+// that the mock keeps answering like Elasticsearch.
+func TestConformance(t *testing.T) {
+	logstest.TestProvider(t, esRepository)
+}
+
 func TestFilterContainerLogs(t *testing.T) {
 	tests := []testStruct{
 		{
@@ -367,8 +526,8 @@ func TestFilterContainerLogs(t *testing.T) {
 
 	for _, tt := range tests {
 		repository, params := initRepository(t, tt)
-		logList, err := repository.FilterContainerLogs(params)
-		errorHandler(t, tt.TestError, err, tt.TestKeywords, logList, tt.TestName)
+		page, err := repository.FilterContainerLogs(context.Background(), params)
+		errorHandler(t, tt.TestError, err, tt.TestKeywords, page.Logs, tt.TestName)
 	}
 
 }
@@ -458,8 +617,8 @@ func TestFilterLogs(t *testing.T) {
 	}
 	for _, tt := range tests {
 		repository, params := initRepository(t, tt)
-		logList, err := repository.FilterLogs(params)
-		errorHandler(t, tt.TestError, err, tt.TestKeywords, logList, tt.TestName)
+		page, err := repository.FilterLogs(context.Background(), params)
+		errorHandler(t, tt.TestError, err, tt.TestKeywords, page.Logs, tt.TestName)
 	}
 }
 
@@ -479,6 +638,15 @@ func TestLogs(t *testing.T) {
 			nil,
 			[]string{"timestamp", "2021-03-18T06:41:51"},
 		},
+		{
+			"Search the log message for a phrase",
+			false,
+			map[string]string{
+				"Query": "\"Readiness probe\" -failed",
+			},
+			nil,
+			[]string{"Readiness probe"},
+		},
 		{
 			"Filter by Logging level, limitting number of logs to 10",
 			false,
@@ -522,8 +690,8 @@ func TestLogs(t *testing.T) {
 
 	for _, tt := range tests {
 		repository, params := initRepository(t, tt)
-		logList, err := repository.Logs(params)
-		errorHandler(t, tt.TestError, err, tt.TestKeywords, logList, tt.TestName)
+		page, err := repository.Logs(context.Background(), params)
+		errorHandler(t, tt.TestError, err, tt.TestKeywords, page.Logs, tt.TestName)
 	}
 }
 func addParams(params *logs.Parameters, testParams map[string]string) {
@@ -545,6 +713,8 @@ func addParams(params *logs.Parameters, testParams map[string]string) {
 			params.Level = v
 		case "Maxlogs":
 			params.MaxLogs = v
+		case "Query":
+			params.Query = v
 		}
 	}
 }
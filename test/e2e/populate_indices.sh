@@ -13,6 +13,7 @@ insert_infra() {
         "kubernetes.namespace_name": { "type": "keyword" },
         "kubernetes.container_name":{"type":"text","fields":{"raw":{"type":"keyword"}}},
         "kubernetes.pod_name": { "type": "keyword" },
+        "kubernetes.flat_labels": { "type": "keyword" },
         "kubernetes.host" : {"type":"keyword"},
         "kubernetes.pod_id" : {"type":"keyword"},
         "kubernetes.master_url" : {"type":"keyword"},
@@ -45,6 +46,7 @@ insert_app() {
         "kubernetes.namespace_name": { "type": "keyword" },
         "kubernetes.container_name":{"type":"text","fields":{"raw":{"type":"keyword"}}},
         "kubernetes.pod_name": { "type": "keyword" },
+        "kubernetes.flat_labels": { "type": "keyword" },
         "kubernetes.host" : {"type":"keyword"},
         "kubernetes.pod_id" : {"type":"keyword"},
         "kubernetes.master_url" : {"type":"keyword"},
@@ -77,6 +79,7 @@ insert_audit() {
         "kubernetes.namespace_name": { "type": "keyword" },
         "kubernetes.container_name":{"type":"text","fields":{"raw":{"type":"keyword"}}},
         "kubernetes.pod_name": { "type": "keyword" },
+        "kubernetes.flat_labels": { "type": "keyword" },
         "kubernetes.host" : {"type":"keyword"},
         "kubernetes.pod_id" : {"type":"keyword"},
         "kubernetes.master_url" : {"type":"keyword"},